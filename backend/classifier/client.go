@@ -0,0 +1,135 @@
+// Package classifier — HTTP-клиент Python-классификатора обращений (classifier/serve.py, :5055).
+package classifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultURL     = "http://localhost:5055"
+	DefaultTimeout = 2 * time.Second
+)
+
+// ErrUnavailable — классификатор не ответил или вернул ошибку; вызывающий работает без него.
+var ErrUnavailable = errors.New("classifier unavailable")
+
+type Prediction struct {
+	Leaf      string  `json:"leaf"`
+	Score     float64 `json:"score"`
+	DomainKey string  `json:"domain_key"`
+	GroupKey  string  `json:"group_key"`
+	IssueKey  string  `json:"issue_key"`
+	Method    string  `json:"method"`
+}
+
+// Result — ответ /classify и /classify_image (лишние поля serve.py игнорируются).
+type Result struct {
+	Predictions  []Prediction `json:"predictions"`
+	Best         *Prediction  `json:"best"`
+	AIConfidence *float64     `json:"ai_confidence"`
+	Source       string       `json:"source"`
+	NeedText     bool         `json:"need_text"`
+}
+
+// Confidence — уверенность лучшего класса (0, если предсказания нет).
+func (r *Result) Confidence() float64 {
+	if r == nil || r.Best == nil || r.Best.DomainKey == "" {
+		return 0
+	}
+	if r.AIConfidence != nil {
+		return *r.AIConfidence
+	}
+	return r.Best.Score
+}
+
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		HTTP:    &http.Client{Timeout: timeout},
+	}
+}
+
+// FromEnv — CLASSIFIER_URL (по умолчанию localhost:5055) и CLASSIFIER_TIMEOUT_MS.
+// CLASSIFIER_URL=off отключает вызовы: все методы сразу возвращают ErrUnavailable.
+func FromEnv() *Client {
+	url := strings.TrimSpace(os.Getenv("CLASSIFIER_URL"))
+	if url == "" {
+		url = DefaultURL
+	}
+	if strings.EqualFold(url, "off") {
+		url = ""
+	}
+	timeout := DefaultTimeout
+	if ms, err := strconv.Atoi(os.Getenv("CLASSIFIER_TIMEOUT_MS")); err == nil && ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	return NewClient(url, timeout)
+}
+
+// ClassifyText — POST /classify {"text", "top_k"}.
+func (c *Client) ClassifyText(ctx context.Context, text string, topK int) (*Result, error) {
+	body, _ := json.Marshal(map[string]interface{}{"text": text, "top_k": topK})
+	return c.post(ctx, "/classify", "application/json", bytes.NewReader(body))
+}
+
+// ClassifyImage — POST /classify_image, multipart-поле "image".
+func (c *Client) ClassifyImage(ctx context.Context, filename string, image io.Reader, topK int) (*Result, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("top_k", strconv.Itoa(topK))
+	part, err := mw.CreateFormFile("image", filepath.Base(filename))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, image); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return c.post(ctx, "/classify_image", mw.FormDataContentType(), &buf)
+}
+
+func (c *Client) post(ctx context.Context, path, contentType string, body io.Reader) (*Result, error) {
+	if c == nil || c.BaseURL == "" {
+		return nil, ErrUnavailable
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s returned %d", ErrUnavailable, path, resp.StatusCode)
+	}
+	var out Result
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("%w: decode: %v", ErrUnavailable, err)
+	}
+	return &out, nil
+}
@@ -0,0 +1,102 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/classify" || r.Method != http.MethodPost {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Text string `json:"text"`
+			TopK int    `json:"top_k"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Text != "яма на дороге" || body.TopK != 3 {
+			t.Errorf("unexpected body %+v", body)
+		}
+		_, _ = io.WriteString(w, `{"predictions":[{"leaf":"roads.surface.pothole","score":0.81,"domain_key":"roads","group_key":"surface","issue_key":"pothole","method":"tfidf_lr"}],
+			"best":{"leaf":"roads.surface.pothole","score":0.81,"domain_key":"roads","group_key":"surface","issue_key":"pothole","method":"tfidf_lr"},
+			"ai_confidence":0.81,"source":"text"}`)
+	}))
+	defer srv.Close()
+
+	res, err := NewClient(srv.URL, time.Second).ClassifyText(context.Background(), "яма на дороге", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Best == nil || res.Best.DomainKey != "roads" || res.Best.IssueKey != "pothole" {
+		t.Fatalf("unexpected best %+v", res.Best)
+	}
+	if res.Confidence() != 0.81 {
+		t.Fatalf("confidence = %v", res.Confidence())
+	}
+}
+
+func TestClassifyImageSendsMultipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/classify_image" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		f, hdr, err := r.FormFile("image")
+		if err != nil {
+			// Fatalf нельзя вызывать из горутины сервера
+			t.Errorf("image field: %v", err)
+			http.Error(w, "no image", http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		if hdr.Filename != "photo.jpg" || string(data) != "jpeg-bytes" {
+			t.Errorf("unexpected file %s %q", hdr.Filename, data)
+		}
+		_, _ = io.WriteString(w, `{"ok":true,"need_text":false,"source":"image_visual",
+			"best":{"leaf":"utilities.light.off","score":0.4,"domain_key":"utilities","method":"clip_visual"},"ai_confidence":0.4}`)
+	}))
+	defer srv.Close()
+
+	res, err := NewClient(srv.URL, time.Second).ClassifyImage(context.Background(), "/uploads/photo.jpg", strings.NewReader("jpeg-bytes"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Source != "image_visual" || res.Best.DomainKey != "utilities" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestClassifierDownOrSlow(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	cases := map[string]*Client{
+		"timeout":  NewClient(slow.URL, 50*time.Millisecond),
+		"500":      NewClient(failing.URL, time.Second),
+		"disabled": NewClient("", time.Second),
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			res, err := c.ClassifyText(context.Background(), "текст", 3)
+			if !errors.Is(err, ErrUnavailable) {
+				t.Fatalf("expected ErrUnavailable, got %v", err)
+			}
+			if res.Confidence() != 0 {
+				t.Fatal("nil result must have zero confidence")
+			}
+		})
+	}
+}
@@ -12,6 +12,13 @@ import (
 
 var DB *sql.DB
 
+var connString string
+
+// ConnString — строка подключения к БД (для отдельных соединений, например LISTEN).
+func ConnString() string {
+	return connString
+}
+
 func ConnectDB() {
 	connStr := os.Getenv("DATABASE_URL")
 	autoCreateDB := false
@@ -20,6 +27,8 @@ func ConnectDB() {
 		autoCreateDB = true
 	}
 
+	connString = connStr
+
 	var err error
 	DB, err = sql.Open("postgres", connStr)
 	if err != nil {
@@ -77,6 +77,13 @@ func PostGISAvailable() bool {
 	return err == nil && ok
 }
 
+// TrigramAvailable returns true if pg_trgm extension is installed.
+func TrigramAvailable() bool {
+	var ok bool
+	err := DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`).Scan(&ok)
+	return err == nil && ok
+}
+
 func NearbyMarkersPostGIS(lat, lng float64, radiusM int, limit int) ([]int, error) {
 	if !PostGISAvailable() {
 		return nil, fmt.Errorf("postgis unavailable")
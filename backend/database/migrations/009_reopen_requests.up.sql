@@ -0,0 +1,16 @@
+-- Оспаривание решения жителями: владелец или несколько поддержавших переоткрывают обращение
+
+CREATE TABLE IF NOT EXISTS marker_reopen_requests (
+  id SERIAL PRIMARY KEY,
+  marker_id INTEGER NOT NULL REFERENCES markers(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  is_owner BOOLEAN NOT NULL DEFAULT FALSE,
+  reason TEXT NOT NULL,
+  image_url TEXT NOT NULL,
+  outcome VARCHAR(20) NOT NULL DEFAULT 'pending',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  closed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_marker_reopen_requests_marker ON marker_reopen_requests(marker_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_marker_reopen_requests_pending
+  ON marker_reopen_requests(marker_id, user_id) WHERE outcome = 'pending';
@@ -0,0 +1,12 @@
+-- Эскалации просроченных SLA: одна запись на (метка, тип срока, уровень, срок) — повторный запуск не дублирует уведомления
+
+CREATE TABLE IF NOT EXISTS sla_escalations (
+  id SERIAL PRIMARY KEY,
+  marker_id INTEGER NOT NULL REFERENCES markers(id) ON DELETE CASCADE,
+  kind VARCHAR(20) NOT NULL,
+  level SMALLINT NOT NULL,
+  due_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE(marker_id, kind, level, due_at)
+);
+CREATE INDEX IF NOT EXISTS idx_sla_escalations_marker ON sla_escalations(marker_id, created_at DESC);
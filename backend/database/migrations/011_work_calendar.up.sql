@@ -0,0 +1,44 @@
+-- Производственный календарь для SLA: праздники и перенесённые рабочие дни (общие или для ведомства),
+-- выходные дни недели ведомства и точка отсчёта сроков для пересчёта при изменении календаря
+
+CREATE TABLE IF NOT EXISTS work_calendar_days (
+  id SERIAL PRIMARY KEY,
+  day DATE NOT NULL,
+  department_id INTEGER REFERENCES departments(id) ON DELETE CASCADE,
+  is_workday BOOLEAN NOT NULL DEFAULT FALSE,
+  name_ru VARCHAR(200) NOT NULL DEFAULT '',
+  created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_work_calendar_day_dept ON work_calendar_days(day, COALESCE(department_id, 0));
+
+-- ISO-дни недели (1 = пн … 7 = вс); NULL — общий календарь (сб, вс)
+ALTER TABLE departments ADD COLUMN IF NOT EXISTS weekend_days SMALLINT[];
+
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS response_sla_from TIMESTAMP;
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS resolution_sla_from TIMESTAMP;
+
+UPDATE markers SET response_sla_from = created_at
+WHERE response_sla_from IS NULL AND response_due_at IS NOT NULL;
+UPDATE markers SET resolution_sla_from = COALESCE(updated_at, created_at)
+WHERE resolution_sla_from IS NULL AND resolution_due_at IS NOT NULL;
+
+INSERT INTO work_calendar_days (day, name_ru)
+SELECT v.day, v.name_ru
+FROM (VALUES
+  (DATE '2026-01-01', 'Новогодние каникулы'),
+  (DATE '2026-01-02', 'Новогодние каникулы'),
+  (DATE '2026-01-05', 'Новогодние каникулы'),
+  (DATE '2026-01-06', 'Новогодние каникулы'),
+  (DATE '2026-01-07', 'Рождество Христово'),
+  (DATE '2026-01-08', 'Новогодние каникулы'),
+  (DATE '2026-01-09', 'Новогодние каникулы'),
+  (DATE '2026-02-23', 'День защитника Отечества'),
+  (DATE '2026-03-09', 'Международный женский день (перенос)'),
+  (DATE '2026-05-01', 'Праздник Весны и Труда'),
+  (DATE '2026-05-11', 'День Победы (перенос)'),
+  (DATE '2026-06-12', 'День России'),
+  (DATE '2026-11-04', 'День народного единства'),
+  (DATE '2026-12-31', 'Новогодние каникулы')
+) AS v(day, name_ru)
+WHERE NOT EXISTS (SELECT 1 FROM work_calendar_days LIMIT 1);
@@ -0,0 +1,17 @@
+-- Пауза срока решения, пока ведомство ждёт уточнений от заявителя (official_responses.response_type = 'info_requested')
+
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS sla_paused_at TIMESTAMP;
+-- накопленная пауза текущего цикла решения (обнуляется, когда срок назначается заново)
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS sla_paused_seconds BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS sla_pauses (
+  id SERIAL PRIMARY KEY,
+  marker_id INTEGER NOT NULL REFERENCES markers(id) ON DELETE CASCADE,
+  official_response_id INTEGER REFERENCES official_responses(id) ON DELETE SET NULL,
+  paused_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  paused_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  resumed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  resumed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_sla_pauses_marker ON sla_pauses(marker_id, paused_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_sla_pauses_open ON sla_pauses(marker_id) WHERE resumed_at IS NULL;
@@ -0,0 +1,10 @@
+-- Ответственное ведомство метки: назначается при одобрении по domain_key, модератор может переназначить
+
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS department_id INTEGER REFERENCES departments(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_markers_department ON markers(department_id) WHERE department_id IS NOT NULL;
+
+UPDATE markers m SET department_id = (
+  SELECT d.id FROM departments d WHERE m.domain_key = ANY(d.category_keys) ORDER BY d.id LIMIT 1
+)
+WHERE m.department_id IS NULL
+  AND LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'resolved', 'reopened');
@@ -0,0 +1,15 @@
+-- Переписка по метке: несколько ответов ведомств, ответы заявителя и вложения
+
+ALTER TABLE official_responses ALTER COLUMN department_id DROP NOT NULL;
+ALTER TABLE official_responses ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES official_responses(id) ON DELETE SET NULL;
+-- department — ответ ведомства, citizen — ответ заявителя
+ALTER TABLE official_responses ADD COLUMN IF NOT EXISTS author_kind VARCHAR(20) NOT NULL DEFAULT 'department';
+CREATE INDEX IF NOT EXISTS idx_official_responses_thread ON official_responses(marker_id, created_at ASC);
+
+CREATE TABLE IF NOT EXISTS official_response_attachments (
+  id SERIAL PRIMARY KEY,
+  response_id INTEGER NOT NULL REFERENCES official_responses(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_official_response_attachments ON official_response_attachments(response_id);
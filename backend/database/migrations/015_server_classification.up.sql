@@ -0,0 +1,21 @@
+-- Серверная классификация при создании метки и расхождения с тем, что прислал клиент
+
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS ai_domain_key VARCHAR(80);
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS ai_group_key VARCHAR(80);
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS ai_issue_key VARCHAR(120);
+-- text | image_visual | image_ocr | … (поле source ответа классификатора); unavailable — классификатор не ответил
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS ai_source VARCHAR(40);
+
+CREATE TABLE IF NOT EXISTS classification_disagreements (
+  id SERIAL PRIMARY KEY,
+  marker_id INTEGER NOT NULL REFERENCES markers(id) ON DELETE CASCADE,
+  client_domain_key VARCHAR(80),
+  client_issue_key VARCHAR(120),
+  client_confidence DOUBLE PRECISION,
+  server_domain_key VARCHAR(80),
+  server_issue_key VARCHAR(120),
+  server_confidence DOUBLE PRECISION,
+  server_source VARCHAR(40),
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_classification_disagreements_marker ON classification_disagreements(marker_id);
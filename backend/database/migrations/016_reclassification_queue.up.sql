@@ -0,0 +1,27 @@
+-- Очередь повторной классификации меток (воркеры забирают задания через FOR UPDATE SKIP LOCKED)
+
+CREATE TABLE IF NOT EXISTS reclassification_jobs (
+  id SERIAL PRIMARY KEY,
+  marker_id INTEGER NOT NULL REFERENCES markers(id) ON DELETE CASCADE,
+  status VARCHAR(20) NOT NULL DEFAULT 'queued',
+  reason VARCHAR(200) NOT NULL DEFAULT '',
+  requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  attempts SMALLINT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  run_after TIMESTAMP NOT NULL DEFAULT NOW(),
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  started_at TIMESTAMP,
+  finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_reclassification_jobs_queued ON reclassification_jobs(run_after, id) WHERE status = 'queued';
+-- одно активное задание на метку
+CREATE UNIQUE INDEX IF NOT EXISTS uq_reclassification_jobs_active ON reclassification_jobs(marker_id)
+  WHERE status IN ('queued', 'running');
+
+-- предложение классификатора хранится рядом с текущей категорией до решения модератора
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS suggested_domain_key VARCHAR(80);
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS suggested_group_key VARCHAR(80);
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS suggested_issue_key VARCHAR(120);
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS suggested_confidence DOUBLE PRECISION;
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS suggested_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_markers_suggested_domain ON markers(suggested_domain_key) WHERE suggested_domain_key IS NOT NULL;
@@ -0,0 +1,20 @@
+-- Разметка модераторов для дообучения классификатора: смена domain_key или подтверждение ответа ИИ
+
+CREATE TABLE IF NOT EXISTS classification_labels (
+  id SERIAL PRIMARY KEY,
+  marker_id INTEGER NOT NULL REFERENCES markers(id) ON DELETE CASCADE,
+  -- текст обращения на момент разметки
+  text TEXT NOT NULL,
+  domain_key VARCHAR(80) NOT NULL,
+  previous_domain_key VARCHAR(80),
+  ai_domain_key VARCHAR(80),
+  ai_confidence DOUBLE PRECISION,
+  -- NULL — для метки не было ответа классификатора
+  ai_correct BOOLEAN,
+  -- moderator_change | ai_confirmed | suggestion_accepted
+  source VARCHAR(30) NOT NULL,
+  labeled_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_classification_labels_marker ON classification_labels(marker_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_classification_labels_created ON classification_labels(created_at);
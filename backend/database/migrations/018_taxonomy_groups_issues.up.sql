@@ -0,0 +1,24 @@
+-- Иерархия таксономии: направление → группа → проблема (markers.group_key / markers.issue_key)
+
+CREATE TABLE IF NOT EXISTS classification_groups (
+  domain_key VARCHAR(80) NOT NULL REFERENCES classification_domains(domain_key) ON DELETE CASCADE ON UPDATE CASCADE,
+  group_key VARCHAR(80) NOT NULL,
+  label_ru VARCHAR(255) NOT NULL,
+  training_phrases JSONB NOT NULL DEFAULT '[]',
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (domain_key, group_key)
+);
+
+CREATE TABLE IF NOT EXISTS classification_issues (
+  domain_key VARCHAR(80) NOT NULL,
+  group_key VARCHAR(80) NOT NULL,
+  issue_key VARCHAR(120) NOT NULL,
+  label_ru VARCHAR(255) NOT NULL,
+  training_phrases JSONB NOT NULL DEFAULT '[]',
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (domain_key, group_key, issue_key),
+  FOREIGN KEY (domain_key, group_key) REFERENCES classification_groups(domain_key, group_key)
+    ON DELETE CASCADE ON UPDATE CASCADE
+);
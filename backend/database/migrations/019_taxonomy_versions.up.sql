@@ -0,0 +1,10 @@
+-- Нумерованные снимки таксономии (направления, группы, проблемы) для сравнения и отката
+
+CREATE TABLE IF NOT EXISTS taxonomy_versions (
+  version SERIAL PRIMARY KEY,
+  snapshot JSONB NOT NULL,
+  -- domain_created:roads, rollback:3, seed, …
+  reason VARCHAR(255),
+  created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Настройки поиска дублей по направлениям; NULL — значения по умолчанию (DUPLICATE_THRESHOLD / DUPLICATE_RADIUS_M)
+
+ALTER TABLE classification_domains ADD COLUMN IF NOT EXISTS duplicate_threshold DOUBLE PRECISION;
+ALTER TABLE classification_domains ADD COLUMN IF NOT EXISTS duplicate_radius_m INTEGER;
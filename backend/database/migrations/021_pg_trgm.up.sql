@@ -0,0 +1,4 @@
+-- pg_trgm необязателен, как и PostGIS: без него похожесть текста считается в Go (utils.TrigramSimilarity)
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_markers_text_trgm ON markers USING GIN (text gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- Слияние дублей: поглощённое обращение получает статус merged и ссылку на основное
+
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS merged_into INTEGER REFERENCES markers(id) ON DELETE SET NULL;
+ALTER TABLE markers ADD COLUMN IF NOT EXISTS merged_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_markers_merged_into ON markers(merged_into) WHERE merged_into IS NOT NULL;
@@ -0,0 +1,8 @@
+-- События realtime, не влезающие в payload NOTIFY (8000 байт): узлы читают их по id
+
+CREATE TABLE IF NOT EXISTS realtime_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_realtime_outbox_created ON realtime_outbox(created_at);
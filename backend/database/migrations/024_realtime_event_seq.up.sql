@@ -0,0 +1,3 @@
+-- Сквозные номера realtime-событий для всех экземпляров backend (догрузка после переподключения)
+
+CREATE SEQUENCE IF NOT EXISTS realtime_event_seq;
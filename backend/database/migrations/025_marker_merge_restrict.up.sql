@@ -0,0 +1,5 @@
+-- Основное обращение со слитыми дублями удалить нельзя: иначе у дублей остаётся статус merged без ссылки
+
+ALTER TABLE markers DROP CONSTRAINT IF EXISTS markers_merged_into_fkey;
+ALTER TABLE markers ADD CONSTRAINT markers_merged_into_fkey
+  FOREIGN KEY (merged_into) REFERENCES markers(id) ON DELETE RESTRICT;
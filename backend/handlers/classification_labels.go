@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+// PutMarkerClassificationHandler PUT /api/markers/{id}/classification — модератор меняет категорию метки.
+// Решение сохраняется в разметке для дообучения классификатора.
+func PutMarkerClassificationHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	var body struct {
+		DomainKey string `json:"domain_key"`
+		GroupKey  string `json:"group_key"`
+		IssueKey  string `json:"issue_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if strings.TrimSpace(body.DomainKey) == "" {
+		respondWithError(w, http.StatusBadRequest, "domain_key required")
+		return
+	}
+	actor := actorPtrFromRequest(r)
+	old, err := repositories.SetMarkerClassification(markerID, body.DomainKey, body.GroupKey, body.IssueKey,
+		repositories.LabelModeratorChange, actor)
+	respondWithClassificationResult(w, markerID, old, "marker_classification_change", actor, err)
+}
+
+// ConfirmMarkerClassificationHandler POST /api/markers/{id}/classification/confirm — модератор подтверждает ответ ИИ.
+func ConfirmMarkerClassificationHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	actor := actorPtrFromRequest(r)
+	old, err := repositories.ConfirmMarkerAIClassification(markerID, actor)
+	respondWithClassificationResult(w, markerID, old, "marker_classification_confirm", actor, err)
+}
+
+func respondWithClassificationResult(w http.ResponseWriter, markerID int, old, action string, actor *int, err error) {
+	switch {
+	case repositories.IsStaleTaxonomyError(err):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, repositories.ErrNoAIClassification):
+		respondWithError(w, http.StatusConflict, "Marker has no AI classification")
+		return
+	case err == sql.ErrNoRows:
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	m, err := repositories.NewMarkerRepository().GetByID(markerID)
+	if err != nil || m == nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	tid := markerID
+	repositories.InsertAuditLog(actor, action, "marker", &tid, map[string]interface{}{
+		"old": old, "new": m.DomainKey,
+	})
+	if old != m.DomainKey {
+		broadcastMarkerUpdated(markerID, map[string]interface{}{
+			"domain_key": m.DomainKey, "group_key": m.GroupKey, "issue_key": m.IssueKey,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":         "success",
+		"marker_id":      markerID,
+		"domain_key":     m.DomainKey,
+		"group_key":      m.GroupKey,
+		"issue_key":      m.IssueKey,
+		"old_domain_key": old,
+	})
+}
+
+// AdminExportClassificationLabelsHandler GET /api/admin/classification-labels/export?since=YYYY-MM-DD&domain_key=
+// CSV для classifier/train.py (data/user_labeled.csv): колонки text,label и дополнительно confidence, ai_correct.
+func AdminExportClassificationLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	_, isAdmin, ok := adminActorFromDB(r.Context())
+	if !ok || !isAdmin {
+		respondWithError(w, http.StatusForbidden, "Admin only")
+		return
+	}
+	q := r.URL.Query()
+	var f repositories.ClassificationLabelFilter
+	if s := strings.TrimSpace(q.Get("since")); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "since: expected YYYY-MM-DD")
+			return
+		}
+		f.Since = &t
+	}
+	f.DomainKey = q.Get("domain_key")
+	labels, err := repositories.ListClassificationLabelsForExport(f)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="user_labeled.csv"`)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"text", "label", "confidence", "ai_correct"})
+	for _, l := range labels {
+		conf, correct := "", ""
+		if l.AIConfidence != nil {
+			conf = strconv.FormatFloat(*l.AIConfidence, 'f', 4, 64)
+		}
+		if l.AICorrect != nil {
+			correct = strconv.FormatBool(*l.AICorrect)
+		}
+		// train.py читает по строке на пример — переводы строк в тексте схлопываем
+		text := strings.Join(strings.Fields(l.Text), " ")
+		_ = cw.Write([]string{text, l.DomainKey, conf, correct})
+	}
+	cw.Flush()
+}
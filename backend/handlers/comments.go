@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -77,6 +78,9 @@ func CreateMarkerCommentHandler(w http.ResponseWriter, r *http.Request) {
 		_, _ = nrepo.Create(ownerID, "marker_comment", &mid, "Новый комментарий",
 			"К вашему обращению добавили комментарий.\n\n«"+snip+"»")
 	}
+	if errOwner == nil && ownerID == userID {
+		resumeSLAOnOwnerReply(markerID, userID)
+	}
 	commentMid := markerID
 	services.AwardPoints(userID, "comment_added", services.PointsCommentAdded, "Комментарий к обращению", &commentMid)
 
@@ -140,3 +144,17 @@ func GetComments(w http.ResponseWriter, r *http.Request) {
 		"status":   "success",
 	})
 }
+
+// resumeSLAOnOwnerReply — ответ заявителя снимает паузу, выставленную запросом уточнений ведомства.
+func resumeSLAOnOwnerReply(markerID, ownerID int) {
+	actor := ownerID
+	d, err := repositories.ResumeMarkerSLA(markerID, &actor)
+	if err != nil {
+		log.Printf("sla resume marker %d: %v", markerID, err)
+		return
+	}
+	if d > 0 {
+		services.NotifyDepartmentReps(markerID, "sla_resumed", "Заявитель ответил",
+			"Заявитель ответил на запрос уточнений, срок решения снова идёт.")
+	}
+}
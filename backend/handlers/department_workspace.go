@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/middleware"
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+// requireDepartmentScope — метка назначена ведомству текущего представителя.
+// Права модератора здесь не учитываются: для них есть /api/markers/{id}/status.
+func requireDepartmentScope(w http.ResponseWriter, r *http.Request) (uid, markerID int, ok bool) {
+	uid, ok = middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return 0, 0, false
+	}
+	deptID, isRep, err := repositories.UserDepartment(uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return 0, 0, false
+	}
+	if !isRep || deptID <= 0 {
+		respondWithError(w, http.StatusForbidden, "Department representatives only")
+		return 0, 0, false
+	}
+	markerDept, err := repositories.MarkerDepartmentID(markerID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return 0, 0, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return 0, 0, false
+	}
+	if markerDept != deptID {
+		respondWithError(w, http.StatusForbidden, "Обращение назначено другому ведомству")
+		return 0, 0, false
+	}
+	return uid, markerID, true
+}
+
+// DepartmentUpdateMarkerStatusHandler PATCH /api/department/markers/{id}/status — перевод метки своим ведомством
+// (в работу / решено / повторно в работу) по правилам services.markerWorkflow.
+func DepartmentUpdateMarkerStatusHandler(w http.ResponseWriter, r *http.Request) {
+	uid, markerID, ok := requireDepartmentScope(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Status        string  `json:"status"`
+		Note          *string `json:"note"`
+		ImageAfterURL string  `json:"image_after_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	status := services.NormalizeMarkerStatus(body.Status)
+	if !services.IsKnownMarkerStatus(status) {
+		respondWithError(w, http.StatusBadRequest, "Invalid status: use "+strings.Join(services.KnownMarkerStatuses(), ", "))
+		return
+	}
+	var notePtr *string
+	if body.Note != nil {
+		if t := strings.TrimSpace(*body.Note); t != "" {
+			notePtr = &t
+		}
+	}
+	imageAfterURL := strings.TrimSpace(body.ImageAfterURL)
+	if imageAfterURL != "" && !strings.HasPrefix(imageAfterURL, "/uploads/") {
+		respondWithError(w, http.StatusBadRequest, "image_after_url must be an uploaded file")
+		return
+	}
+	actor := services.WorkflowActor{UserID: uid, DepartmentRep: true}
+	if err := applyMarkerStatusUpdate(markerID, status, notePtr, actor, imageAfterURL); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Marker not found")
+			return
+		}
+		if werr, ok := err.(*services.WorkflowError); ok {
+			respondWithWorkflowError(w, werr)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        "success",
+		"id":            markerID,
+		"marker_status": status,
+	})
+}
+
+// DepartmentAfterPhotoHandler POST /api/department/markers/{id}/after-photo — фото «после» без смены статуса.
+func DepartmentAfterPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	uid, markerID, ok := requireDepartmentScope(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		ImageAfterURL string `json:"image_after_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	url := strings.TrimSpace(body.ImageAfterURL)
+	if !strings.HasPrefix(url, "/uploads/") {
+		respondWithError(w, http.StatusBadRequest, "image_after_url must be an uploaded file")
+		return
+	}
+	repo := repositories.NewMarkerRepository()
+	old, _ := repo.GetImageAfterURL(markerID)
+	if err := repo.UpdateMarkerMeta(markerID, uid, url, ""); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Marker not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	actor := uid
+	_ = repositories.InsertMarkerChange(markerID, "image_after_url", old, url, &actor)
+	broadcastMarkerUpdated(markerID, map[string]interface{}{"image_after_url": url})
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":          "success",
+		"id":              markerID,
+		"image_after_url": url,
+	})
+}
+
+// DepartmentStatsHandler GET /api/department/stats — SLA-сводка своего ведомства.
+func DepartmentStatsHandler(w http.ResponseWriter, r *http.Request) {
+	_, deptID, ok := departmentFromRequest(w, r)
+	if !ok {
+		return
+	}
+	stats, err := repositories.GetDepartmentSLAStats(deptID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/middleware"
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+// PutMarkerDepartmentHandler — переназначение ответственного ведомства (модератор), причина обязательна.
+func PutMarkerDepartmentHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	var body struct {
+		DepartmentID int    `json:"department_id"`
+		Reason       string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	reason := strings.TrimSpace(body.Reason)
+	if body.DepartmentID <= 0 || reason == "" {
+		respondWithError(w, http.StatusBadRequest, "department_id and reason required")
+		return
+	}
+	uid, _ := middleware.GetUserIDFromContext(r.Context())
+	var actorPtr *int
+	if uid > 0 {
+		actorPtr = &uid
+	}
+	oldID, err := repositories.ReassignMarkerDepartment(markerID, body.DepartmentID, reason, actorPtr)
+	if errors.Is(err, repositories.ErrDepartmentNotFound) {
+		respondWithError(w, http.StatusBadRequest, "Department not found")
+		return
+	}
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if oldID != body.DepartmentID {
+		tid := markerID
+		repositories.InsertAuditLog(actorPtr, "marker_department_reassign", "marker", &tid, map[string]interface{}{
+			"old": oldID, "new": body.DepartmentID, "reason": reason,
+		})
+		broadcastMarkerUpdated(markerID, map[string]interface{}{"department_id": body.DepartmentID})
+		services.NotifyDepartmentReps(markerID, "department_assigned", "Обращение передано вашему ведомству",
+			"Причина: "+truncSnippet(reason, 200))
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":            "success",
+		"marker_id":         markerID,
+		"department_id":     body.DepartmentID,
+		"old_department_id": oldID,
+	})
+}
+
+// DepartmentQueueHandler GET /api/department/markers — очередь ведомства.
+// Представитель видит только своё ведомство; модератор выбирает его через ?department_id=.
+func DepartmentQueueHandler(w http.ResponseWriter, r *http.Request) {
+	uid, deptID, ok := departmentFromRequest(w, r)
+	if !ok {
+		return
+	}
+	listQ := moderationListQueryFromRequest(r, uid)
+	listQ.DepartmentID = deptID
+	if listQ.Status == "" {
+		listQ.Unresolved = true
+	}
+	markers, total, err := repositories.ListModerationMarkers(listQ)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        "success",
+		"department_id": deptID,
+		"markers":       markers,
+		"total":         total,
+		"page":          listQ.Page,
+		"page_size":     listQ.PageSize,
+		"count":         len(markers),
+	})
+}
+
+// departmentFromRequest — ведомство запроса: своё у представителя, ?department_id= у модератора.
+func departmentFromRequest(w http.ResponseWriter, r *http.Request) (uid, deptID int, ok bool) {
+	uid, ok = middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+	deptID, isRep, err := repositories.UserDepartment(uid)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return 0, 0, false
+	}
+	if isRep && deptID > 0 {
+		return uid, deptID, true
+	}
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Department representatives only")
+		return 0, 0, false
+	}
+	deptID, _ = strconv.Atoi(r.URL.Query().Get("department_id"))
+	if deptID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "department_id required")
+		return 0, 0, false
+	}
+	return uid, deptID, true
+}
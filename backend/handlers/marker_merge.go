@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+// MergeMarkersHandler POST /api/moderation/markers/{id}/merge — модератор сливает дубли в обращение {id}.
+// Тело: {"source_ids": [..], "note": "..."}.
+func MergeMarkersHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	canonicalID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || canonicalID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	var body struct {
+		SourceIDs []int  `json:"source_ids"`
+		Note      string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	actor := actorPtrFromRequest(r)
+	res, err := repositories.MergeMarkers(canonicalID, body.SourceIDs, body.Note, actor)
+	var srcErr *repositories.MergeSourceError
+	switch {
+	case errors.Is(err, repositories.ErrMergeNoSources), errors.Is(err, repositories.ErrMergeTooMany),
+		errors.Is(err, repositories.ErrMergeIntoSelf):
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, repositories.ErrMergeCanonicalDone), errors.Is(err, repositories.ErrMergeCanonicalClosed):
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	case errors.As(err, &srcErr):
+		respondWithError(w, http.StatusConflict, srcErr.Error())
+		return
+	case err == sql.ErrNoRows:
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return
+	case err != nil:
+		log.Printf("merge markers into %d: %v", canonicalID, err)
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	mergedIDs := make([]int, 0, len(res.Merged))
+	for _, m := range res.Merged {
+		mergedIDs = append(mergedIDs, m.ID)
+	}
+	tid := canonicalID
+	repositories.InsertAuditLog(actor, "marker_merge", "marker", &tid, map[string]interface{}{
+		"merged_ids":              mergedIDs,
+		"note":                    body.Note,
+		"supports_moved":          res.SupportsMoved,
+		"comments_moved":          res.CommentsMoved,
+		"reviews_moved":           res.ReviewsMoved,
+		"favorites_moved":         res.FavoritesMoved,
+		"geo_notifications_moved": res.GeoNotifications,
+	})
+
+	nrepo := repositories.NewNotificationRepository()
+	actorID := 0
+	if actor != nil {
+		actorID = *actor
+	}
+	for _, m := range res.Merged {
+		if m.OwnerID > 0 && m.OwnerID != actorID {
+			mid := canonicalID
+			if _, errN := nrepo.Create(m.OwnerID, "marker_merged", &mid, "Обращение объединено",
+				"Ваше обращение #"+strconv.Itoa(m.ID)+" объединено с похожим обращением #"+strconv.Itoa(canonicalID)+
+					". Ваша поддержка перенесена, следите за статусом там.\n\n«"+truncSnippet(m.Text, 120)+"»"); errN != nil {
+				log.Printf("notification create (merged): %v", errN)
+			}
+		}
+		broadcastMarkerStatusChanged(m.ID, m.OldStatus, map[string]interface{}{
+			"status":      services.StatusMerged,
+			"merged_into": canonicalID,
+		})
+	}
+	broadcastMarkerUpdated(canonicalID, map[string]interface{}{"merged_from": mergedIDs})
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "success",
+		"result": res,
+	})
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/middleware"
+	"backend/repositories"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// ListReopenRequestsHandler GET /api/markers/{id}/reopen-requests — текущие заявки на оспаривание решения.
+func ListReopenRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	marker, err := repositories.NewMarkerRepository().GetByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return
+	}
+	list, err := repositories.ListPendingReopenRequests(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if list == nil {
+		list = []repositories.ReopenRequest{}
+	}
+	resp := map[string]interface{}{
+		"requests":    list,
+		"count":       len(list),
+		"threshold":   repositories.ReopenSupportersThreshold,
+		"can_request": false,
+	}
+	if marker.ResolvedAt != nil && strings.EqualFold(marker.Status, services.StatusResolved) {
+		end := repositories.ReopenWindowEnd(*marker.ResolvedAt)
+		resp["window_ends_at"] = end
+		if viewer := middleware.UserIDFromAuthHeader(r); viewer > 0 && time.Now().Before(end) {
+			supported, _ := repositories.NewSupportRepository().UserSupported(id, viewer)
+			resp["can_request"] = viewer == marker.UserID || supported
+		}
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// PostReopenRequestHandler POST /api/markers/{id}/reopen-requests — владелец или поддержавший оспаривает решение.
+// Владелец переоткрывает сразу, поддержавшим нужно набрать ReopenSupportersThreshold заявок.
+func PostReopenRequestHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	var body struct {
+		Reason   string `json:"reason"`
+		ImageURL string `json:"image_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	reason := strings.TrimSpace(body.Reason)
+	imageURL := strings.TrimSpace(body.ImageURL)
+	if reason == "" {
+		respondWithError(w, http.StatusBadRequest, "Опишите, что осталось нерешённым (reason)")
+		return
+	}
+	if utils.ContainsProfanity(reason) {
+		respondWithError(w, http.StatusBadRequest, "Текст содержит недопустимые выражения")
+		return
+	}
+	if !strings.HasPrefix(imageURL, "/uploads/") {
+		respondWithError(w, http.StatusBadRequest, "Приложите свежее фото через /api/upload (image_url)")
+		return
+	}
+
+	repo := repositories.NewMarkerRepository()
+	marker, err := repo.GetByID(id)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if !strings.EqualFold(marker.Status, services.StatusResolved) || marker.ResolvedAt == nil {
+		respondWithError(w, http.StatusConflict, "Оспорить можно только решённое обращение")
+		return
+	}
+	if time.Now().After(repositories.ReopenWindowEnd(*marker.ResolvedAt)) {
+		respondWithError(w, http.StatusConflict, "Срок оспаривания решения истёк")
+		return
+	}
+	isOwner := marker.UserID == uid
+	if !isOwner {
+		supported, _ := repositories.NewSupportRepository().UserSupported(id, uid)
+		if !supported {
+			respondWithError(w, http.StatusForbidden, "Оспорить решение может автор или поддержавший обращение")
+			return
+		}
+	}
+
+	_ = repositories.ExpireStaleReopenRequests(id, *marker.ResolvedAt)
+	requestID, err := repositories.CreateReopenRequest(id, uid, isOwner, reason, imageURL)
+	if err != nil {
+		if err == repositories.ErrReopenAlreadyRequested {
+			respondWithError(w, http.StatusConflict, "Вы уже оспорили это решение")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	supporters, _ := repositories.CountPendingSupporterReopenRequests(id)
+	reopen := isOwner || supporters >= repositories.ReopenSupportersThreshold
+	if reopen {
+		if err := reopenContestedMarker(id, uid, reason, imageURL); err != nil {
+			log.Printf("reopen marker=%d: %v", id, err)
+			if errF := repositories.FailReopenRequest(requestID); errF != nil {
+				log.Printf("reopen request=%d fail: %v", requestID, errF)
+			}
+			if werr, ok := err.(*services.WorkflowError); ok {
+				respondWithWorkflowError(w, werr)
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"status":          "success",
+		"reopened":        reopen,
+		"supporter_count": supporters,
+		"threshold":       repositories.ReopenSupportersThreshold,
+	})
+}
+
+// reopenContestedMarker — перевод в reopened: новый срок решения, снятие оспоренного фото «после», уведомление ведомства.
+func reopenContestedMarker(id, uid int, reason, imageURL string) error {
+	note := "Решение оспорено: " + reason
+	actor := services.WorkflowActor{UserID: uid, ContestVerified: true}
+	if err := applyMarkerStatusUpdate(id, services.StatusReopened, &note, actor, ""); err != nil {
+		return err
+	}
+	if err := repositories.CloseReopenRequests(id, services.StatusReopened); err != nil {
+		return err
+	}
+	actorID := uid
+	old, err := repositories.ResetMarkerAfterPhoto(id)
+	if err != nil {
+		return err
+	}
+	if old != "" {
+		if err := repositories.InsertMarkerChange(id, "image_after_url", old, "", &actorID); err != nil {
+			return err
+		}
+	}
+	if err := repositories.InsertMarkerChange(id, "reopen_image_url", "", imageURL, &actorID); err != nil {
+		return err
+	}
+	services.NotifyDepartmentReps(id, "marker_reopened", "🔁 Решение оспорено жителями",
+		"Обращение №"+strconv.Itoa(id)+" переоткрыто: «"+truncSnippet(reason, 200)+"». Срок решения назначен заново.")
+	return nil
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"backend/middleware"
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+func workflowActorFromRequest(r *http.Request) services.WorkflowActor {
+	uid, _ := middleware.GetUserIDFromContext(r.Context())
+	return services.WorkflowActor{
+		UserID:      uid,
+		IsModerator: middleware.GetIsModeratorFromContext(r.Context()),
+		IsAdmin:     middleware.GetIsAdminFromContext(r.Context()),
+	}
+}
+
+func respondWithWorkflowError(w http.ResponseWriter, err *services.WorkflowError) {
+	respondWithJSON(w, err.HTTPStatus(), map[string]string{
+		"error": err.Message,
+		"code":  err.Code,
+	})
+}
+
+// MarkerTransitionsHandler GET /api/markers/{id}/transitions — какие переходы статуса доступны текущему пользователю.
+func MarkerTransitionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	_, status, _, err := repositories.NewMarkerRepository().GetMarkerNotifyMeta(id)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	actor := workflowActorFromRequest(r)
+	actor.DepartmentRep = isMarkerDepartmentRep(actor.UserID, id)
+	list := services.AvailableTransitions(status, actor)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"marker_id":   id,
+		"status":      status,
+		"transitions": list,
+	})
+}
+
+// isMarkerDepartmentRep — пользователь представляет ведомство, назначенное метке.
+func isMarkerDepartmentRep(uid, markerID int) bool {
+	if uid <= 0 {
+		return false
+	}
+	deptID, isRep, err := repositories.UserDepartment(uid)
+	if err != nil || !isRep || deptID <= 0 {
+		return false
+	}
+	markerDept, err := repositories.MarkerDepartmentID(markerID)
+	return err == nil && markerDept == deptID
+}
@@ -15,6 +15,7 @@ import (
 	"backend/database"
 	"backend/middleware"
 	"backend/models"
+	"backend/realtime"
 	"backend/repositories"
 	"backend/services"
 	"backend/utils"
@@ -74,6 +75,34 @@ func GetMarkersHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetMarkerClustersHandler — кластеры публичных меток в bbox для мелких масштабов (?zoom=0..21).
+func GetMarkerClustersHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	layer := strings.TrimSpace(q.Get("layer"))
+	swLat, _ := strconv.ParseFloat(q.Get("sw_lat"), 64)
+	swLng, _ := strconv.ParseFloat(q.Get("sw_lng"), 64)
+	neLat, _ := strconv.ParseFloat(q.Get("ne_lat"), 64)
+	neLng, _ := strconv.ParseFloat(q.Get("ne_lng"), 64)
+	zoom, err := strconv.Atoi(q.Get("zoom"))
+	if err != nil || zoom < 0 || zoom > 21 {
+		respondWithError(w, http.StatusBadRequest, "zoom required (0..21)")
+		return
+	}
+	clusters, err := repositories.NewMarkerRepository().GetPublicMarkerClusters(swLat, swLng, neLat, neLng, zoom, layer)
+	if err != nil {
+		log.Printf("GetMarkerClustersHandler: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"clusters": clusters,
+		"count":    len(clusters),
+		"zoom":     zoom,
+		"cell_deg": repositories.ClusterCellDegrees(zoom),
+		"layer":    layer,
+	})
+}
+
 func GetMyMarkersHandler(w http.ResponseWriter, r *http.Request) {
 	uid, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
@@ -128,8 +157,22 @@ func CreateMarkerHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := repositories.ValidateMarkerTaxonomy(req.DomainKey, req.GroupKey, req.IssueKey); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Некорректная категория: "+err.Error())
+		return
+	}
+	clientDomain := strings.TrimSpace(req.DomainKey)
+
+	// категорию и уверенность считает сервер: значения клиента можно подделать
+	disagreement := services.ClassifyMarkerRequest(r.Context(), &req)
+	if clientDomain == "" && repositories.ValidateMarkerTaxonomy(req.DomainKey, req.GroupKey, req.IssueKey) != nil {
+		// классификатор мог обучиться на старой таксономии — оставляем только направление
+		req.GroupKey, req.IssueKey = "", ""
+	}
+
+	// дубль — по сумме близости, направления и похожести текста, порог задаётся для направления
 	if !req.ForceCreate {
-		nearby, errN := repositories.NewSupportRepository().FindNearby(req.Latitude, req.Longitude, 100, 5)
+		nearby, _, errN := repositories.FindDuplicateCandidates(req.Text, req.DomainKey, req.Latitude, req.Longitude, 5)
 		if errN == nil && len(nearby) > 0 {
 			respondWithJSON(w, http.StatusConflict, map[string]interface{}{
 				"error":           "nearby_exists",
@@ -145,6 +188,12 @@ func CreateMarkerHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 500, "Database error")
 		return
 	}
+	if disagreement != nil {
+		disagreement.MarkerID = id
+		if errD := repositories.InsertClassificationDisagreement(*disagreement); errD != nil {
+			log.Printf("classification disagreement marker=%d: %v", id, errD)
+		}
+	}
 
 	email, _ := repo.GetUserEmail(req.UserID)
 
@@ -176,6 +225,8 @@ func CreateMarkerHandler(w http.ResponseWriter, r *http.Request) {
 			"group_key":     req.GroupKey,
 			"issue_key":     req.IssueKey,
 			"ai_confidence": req.AIConfidence,
+			"ai_domain_key": req.AIDomainKey,
+			"ai_source":     req.AISource,
 		},
 	})
 }
@@ -204,6 +255,10 @@ func DeleteMarkerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err := repo.Delete(id); err != nil {
+		if err == repositories.ErrMarkerHasMergedDuplicates {
+			respondWithError(w, http.StatusConflict, "В это обращение слиты дубли — его нельзя удалить")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
@@ -286,30 +341,49 @@ func PatchMarkerHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// applyMarkerStatusUpdate — одна смена статуса: БД + журнал + уведомления владельцу + карма.
-func applyMarkerStatusUpdate(id int, status string, notePtr *string, actorUserID int, imageAfterURL string) error {
+// applyMarkerStatusUpdate — одна смена статуса: проверка схемы переходов + БД + журнал + уведомления владельцу + карма.
+func applyMarkerStatusUpdate(id int, status string, notePtr *string, actor services.WorkflowActor, imageAfterURL string) error {
 	repo := repositories.NewMarkerRepository()
 	ownerID, oldStatus, snippet, metaErr := repo.GetMarkerNotifyMeta(id)
 	if metaErr != nil {
 		return metaErr
 	}
-	if err := repo.UpdateStatus(id, status, notePtr); err != nil {
-		return err
-	}
 	imageAfterURL = strings.TrimSpace(imageAfterURL)
-	if imageAfterURL != "" && actorUserID > 0 {
-		if err := repo.UpdateMarkerMeta(id, actorUserID, imageAfterURL, ""); err != nil {
-			return err
+	note := ""
+	if notePtr != nil {
+		note = *notePtr
+	}
+	hasImageAfter := imageAfterURL != ""
+	if !hasImageAfter {
+		if cur, err := repo.GetImageAfterURL(id); err == nil && cur != "" {
+			hasImageAfter = true
 		}
 	}
+	if err := services.CheckTransition(oldStatus, status, actor, note, hasImageAfter); err != nil {
+		return err
+	}
+	if err := checkMarkerReviewLock(id, actor); err != nil {
+		return err
+	}
+	actorUserID := actor.UserID
 	var actorPtr *int
 	if actorUserID > 0 {
 		actorPtr = &actorUserID
 	}
-	_ = repositories.InsertMarkerStatusLog(id, oldStatus, status, actorPtr, notePtr)
-	_ = repositories.InsertMarkerChange(id, "status", oldStatus, status, actorPtr)
-	if imageAfterURL != "" {
-		_ = repositories.InsertMarkerChange(id, "image_after_url", "", imageAfterURL, actorPtr)
+	if err := repo.UpdateStatus(id, repositories.MarkerStatusChange{
+		FromStatus:    oldStatus,
+		Status:        status,
+		ModeratorNote: notePtr,
+		ImageAfterURL: imageAfterURL,
+		ActorID:       actorPtr,
+	}); err != nil {
+		if err == repositories.ErrMarkerStatusChanged {
+			return &services.WorkflowError{
+				Code:    "status_conflict",
+				Message: "Статус обращения только что изменили. Обновите карточку и повторите.",
+			}
+		}
+		return err
 	}
 	tid := id
 	auditPayload := map[string]interface{}{"old": oldStatus, "new": status}
@@ -321,7 +395,7 @@ func applyMarkerStatusUpdate(id int, status string, notePtr *string, actorUserID
 	if imageAfterURL != "" {
 		wsPayload["image_after_url"] = imageAfterURL
 	}
-	broadcastMarkerUpdated(id, wsPayload)
+	broadcastMarkerStatusChanged(id, oldStatus, wsPayload)
 	if ownerID > 0 {
 		services.HandleMarkerStatusChange(id, ownerID, oldStatus, status, notePtr, snippet)
 	}
@@ -343,19 +417,23 @@ func UpdateMarkerStatusHandler(w http.ResponseWriter, r *http.Request) {
 		Status        string  `json:"status"`
 		ModeratorNote *string `json:"moderator_note"`
 		ImageAfterURL string  `json:"image_after_url"`
+		// Force — сменить статус, даже если метку сейчас проверяет другой модератор
+		Force bool `json:"force"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 	status := strings.ToLower(strings.TrimSpace(body.Status))
-	allowed := map[string]bool{
-		"pending": true, "approved": true, "rejected": true, "resolved": true, "in_progress": true,
-	}
-	if !allowed[status] {
-		respondWithError(w, http.StatusBadRequest, "Invalid status: use pending, approved, in_progress, rejected, resolved")
+	if !services.IsKnownMarkerStatus(status) {
+		respondWithError(w, http.StatusBadRequest, "Invalid status: use "+strings.Join(services.KnownMarkerStatuses(), ", "))
 		return
 	}
+	actor := workflowActorFromRequest(r)
+	actor.ForceReviewLock = body.Force
+	// чужая проверка, которую перезаписывают через force, — предупреждение в ответе
+	holder, locked := realtime.MarkerReviewLock(id)
+	overridden := locked && holder.UserID != actor.UserID && body.Force
 	var notePtr *string
 	if body.ModeratorNote != nil {
 		t := strings.TrimSpace(*body.ModeratorNote)
@@ -363,17 +441,16 @@ func UpdateMarkerStatusHandler(w http.ResponseWriter, r *http.Request) {
 			notePtr = &t
 		}
 	}
-	if status == "rejected" && notePtr == nil {
-		respondWithError(w, http.StatusBadRequest, "При отклонении укажите причину (moderator_note)")
-		return
-	}
-	actorID, _ := middleware.GetUserIDFromContext(r.Context())
 	imageAfterURL := strings.TrimSpace(body.ImageAfterURL)
-	if err := applyMarkerStatusUpdate(id, status, notePtr, actorID, imageAfterURL); err != nil {
+	if err := applyMarkerStatusUpdate(id, status, notePtr, actor, imageAfterURL); err != nil {
 		if err == sql.ErrNoRows {
 			respondWithError(w, http.StatusNotFound, "Marker not found")
 			return
 		}
+		if werr, ok := err.(*services.WorkflowError); ok {
+			respondWithWorkflowError(w, werr)
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
@@ -385,6 +462,10 @@ func UpdateMarkerStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if imageAfterURL != "" {
 		resp["image_after_url"] = imageAfterURL
 	}
+	if overridden {
+		resp["warning"] = "Статус изменён, хотя обращение проверял другой модератор"
+		resp["review_lock_holder"] = holder
+	}
 	respondWithJSON(w, http.StatusOK, resp)
 }
 
@@ -404,10 +485,7 @@ func BulkUpdateMarkerStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	status := strings.ToLower(strings.TrimSpace(body.Status))
-	allowed := map[string]bool{
-		"pending": true, "approved": true, "rejected": true, "resolved": true, "in_progress": true,
-	}
-	if !allowed[status] {
+	if !services.IsKnownMarkerStatus(status) {
 		respondWithError(w, http.StatusBadRequest, "Invalid status")
 		return
 	}
@@ -438,12 +516,16 @@ func BulkUpdateMarkerStatusHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "При массовом отклонении укажите причину (moderator_note)")
 		return
 	}
-	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	actor := workflowActorFromRequest(r)
 	var ok []int
 	var failed []map[string]interface{}
 	for _, id := range ids {
-		if err := applyMarkerStatusUpdate(id, status, notePtr, actorID, ""); err != nil {
-			failed = append(failed, map[string]interface{}{"id": id, "error": err.Error()})
+		if err := applyMarkerStatusUpdate(id, status, notePtr, actor, ""); err != nil {
+			entry := map[string]interface{}{"id": id, "error": err.Error()}
+			if werr, isWf := err.(*services.WorkflowError); isWf {
+				entry["code"] = werr.Code
+			}
+			failed = append(failed, entry)
 		} else {
 			ok = append(ok, id)
 		}
@@ -35,6 +35,27 @@ func ListModerationMarkersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	moderatorUID, _ := middleware.GetUserIDFromContext(r.Context())
+	listQ := moderationListQueryFromRequest(r, moderatorUID)
+
+	markers, total, err := repositories.ListModerationMarkers(listQ)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "success",
+		"markers":   markers,
+		"total":     total,
+		"page":      listQ.Page,
+		"page_size": listQ.PageSize,
+		"count":     len(markers),
+	})
+}
+
+// moderationListQueryFromRequest — общие фильтры очередей модерации и ведомств из query string.
+func moderationListQueryFromRequest(r *http.Request, moderatorUID int) repositories.ModerationListQuery {
 	q := r.URL.Query()
 	page, _ := strconv.Atoi(q.Get("page"))
 	pageSize, _ := strconv.Atoi(q.Get("page_size"))
@@ -42,8 +63,6 @@ func ListModerationMarkersHandler(w http.ResponseWriter, r *http.Request) {
 		pageSize, _ = strconv.Atoi(q.Get("limit"))
 	}
 
-	moderatorUID, _ := middleware.GetUserIDFromContext(r.Context())
-
 	domainKey := strings.TrimSpace(q.Get("category"))
 	if domainKey == "" {
 		domainKey = strings.TrimSpace(q.Get("domain_key"))
@@ -66,6 +85,9 @@ func ListModerationMarkersHandler(w http.ResponseWriter, r *http.Request) {
 	if n, err := strconv.Atoi(q.Get("supports_min")); err == nil && n > 0 {
 		listQ.MinSupports = n
 	}
+	if n, err := strconv.Atoi(q.Get("department_id")); err == nil && n > 0 {
+		listQ.DepartmentID = n
+	}
 	if listQ.MinSupports == 0 && (q.Get("many_supports") == "1" || q.Get("hot") == "1") {
 		listQ.MinSupports = 3
 	}
@@ -81,19 +103,5 @@ func ListModerationMarkersHandler(w http.ResponseWriter, r *http.Request) {
 			listQ.DateTo = &end
 		}
 	}
-
-	markers, total, err := repositories.ListModerationMarkers(listQ)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Database error")
-		return
-	}
-
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"status":    "success",
-		"markers":   markers,
-		"total":     total,
-		"page":      listQ.Page,
-		"page_size": listQ.PageSize,
-		"count":     len(markers),
-	})
+	return listQ
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"backend/database"
+	"backend/middleware"
+	"backend/realtime"
+	"backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+// checkMarkerReviewLock — метку открыл другой модератор раньше: смена статуса отклоняется,
+// пока актор явно не перезапишет (force). Жители (оспаривание решения) блокировкой не ограничены.
+func checkMarkerReviewLock(markerID int, actor services.WorkflowActor) error {
+	if !actor.HasRole(services.RoleModerator) && !actor.DepartmentRep {
+		return nil
+	}
+	holder, ok := realtime.MarkerReviewLock(markerID)
+	if !ok || holder.UserID == actor.UserID || actor.ForceReviewLock {
+		return nil
+	}
+	who := holder.Name
+	if who == "" {
+		who = "другой модератор"
+	}
+	return &services.WorkflowError{
+		Code:    "marker_locked",
+		Message: "Обращение сейчас проверяет " + who + ". Повторите с force, чтобы изменить статус всё равно.",
+	}
+}
+
+// realtimeUserName — имя модератора для «проверяет X».
+func realtimeUserName(userID int) string {
+	if database.DB == nil {
+		return ""
+	}
+	var name string
+	_ = database.DB.QueryRow(
+		`SELECT COALESCE(NULLIF(TRIM(display_name), ''), email) FROM users WHERE id = $1`, userID,
+	).Scan(&name)
+	return name
+}
+
+// MarkerReviewersHandler GET /api/moderation/markers/{id}/review — кто сейчас проверяет метку.
+func MarkerReviewersHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	respondWithReviewers(w, r, markerID, realtime.MarkerReviewers(markerID))
+}
+
+// StartMarkerReviewHandler POST /api/moderation/markers/{id}/review — открыть метку или продлить отметку
+// (для клиентов без WebSocket, например SSE). Отметка живёт realtime.ReviewLockTTL.
+func StartMarkerReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	uid, _ := middleware.GetUserIDFromContext(r.Context())
+	list := realtime.StartMarkerReview(markerID, uid, realtimeUserName(uid))
+	respondWithReviewers(w, r, markerID, list)
+}
+
+// ReleaseMarkerReviewHandler DELETE /api/moderation/markers/{id}/review — закрыть метку.
+func ReleaseMarkerReviewHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	uid, _ := middleware.GetUserIDFromContext(r.Context())
+	realtime.ReleaseMarkerReview(markerID, uid)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"status": "success", "marker_id": markerID})
+}
+
+func respondWithReviewers(w http.ResponseWriter, r *http.Request, markerID int, list []realtime.Reviewer) {
+	uid, _ := middleware.GetUserIDFromContext(r.Context())
+	resp := map[string]interface{}{
+		"status":       "success",
+		"marker_id":    markerID,
+		"reviewers":    list,
+		"locked_by_me": len(list) > 0 && list[0].UserID == uid,
+	}
+	if len(list) > 0 {
+		resp["lock_holder"] = list[0].UserID
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
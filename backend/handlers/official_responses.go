@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -9,6 +11,7 @@ import (
 
 	"backend/database"
 	"backend/middleware"
+	"backend/models"
 	"backend/repositories"
 	"backend/services"
 
@@ -40,13 +43,14 @@ func ListDepartmentsHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{"departments": list})
 }
 
+// GetOfficialResponseHandler — последний ответ ведомства (краткая карточка метки).
 func GetOfficialResponseHandler(w http.ResponseWriter, r *http.Request) {
 	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil || markerID <= 0 {
 		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
 		return
 	}
-	row, err := fetchOfficialResponse(markerID)
+	row, err := repositories.LatestDepartmentResponse(markerID)
 	if err != nil {
 		respondWithJSON(w, http.StatusOK, map[string]interface{}{"response": nil})
 		return
@@ -54,58 +58,195 @@ func GetOfficialResponseHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{"response": row})
 }
 
+// ListOfficialResponsesHandler GET /api/markers/{id}/official-responses — вся переписка по метке по порядку.
+func ListOfficialResponsesHandler(w http.ResponseWriter, r *http.Request) {
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	list, err := repositories.ListOfficialResponses(markerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"responses": list, "count": len(list)})
+}
+
+// PostOfficialResponseHandler добавляет запись в переписку: ответ ведомства (представитель или модератор)
+// либо ответ заявителя (владелец метки).
 func PostOfficialResponseHandler(w http.ResponseWriter, r *http.Request) {
 	uid, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
-	if !canPostOfficialResponse(r, uid) {
-		respondWithError(w, http.StatusForbidden, "Только представитель ведомства или модератор")
-		return
-	}
 	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil || markerID <= 0 {
 		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
 		return
 	}
-	var body struct {
-		DepartmentID int    `json:"department_id"`
-		ResponseText string `json:"response_text"`
-		ResponseType string `json:"response_type"`
-		PlannedDate  string `json:"planned_date"`
-		ActualDate   string `json:"actual_date"`
-	}
+	var body models.CreateOfficialResponseRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	text := strings.TrimSpace(body.ResponseText)
-	if text == "" || body.DepartmentID <= 0 {
-		respondWithError(w, http.StatusBadRequest, "department_id and response_text required")
+	body.ResponseText = strings.TrimSpace(body.ResponseText)
+	if body.ResponseText == "" {
+		respondWithError(w, http.StatusBadRequest, "response_text required")
 		return
 	}
-	rtype := strings.TrimSpace(body.ResponseType)
-	if rtype == "" {
-		rtype = "info_requested"
-	}
-	var id int
-	err = database.DB.QueryRow(`
-		INSERT INTO official_responses (marker_id, department_id, responded_by, response_text, response_type, planned_date, actual_date)
-		VALUES ($1,$2,$3,$4,$5, NULLIF($6,'')::date, NULLIF($7,'')::date)
-		RETURNING id`,
-		markerID, body.DepartmentID, uid, text, rtype, body.PlannedDate, body.ActualDate,
-	).Scan(&id)
+	if body.Attachments, err = repositories.NormalizeAttachments(body.Attachments); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if body.ParentID != nil && *body.ParentID > 0 {
+		if _, err := repositories.GetOfficialResponse(markerID, *body.ParentID); err != nil {
+			respondWithError(w, http.StatusBadRequest, "parent_id not found in this thread")
+			return
+		}
+	}
+
+	if canPostOfficialResponse(r, uid) {
+		postDepartmentResponse(w, r, uid, markerID, body)
+		return
+	}
+	ownerID, err := repositories.NewMarkerRepository().GetMarkerOwnerUserID(markerID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return
+	}
+	if ownerID != uid {
+		respondWithError(w, http.StatusForbidden, "Только представитель ведомства, модератор или автор обращения")
+		return
+	}
+	postCitizenReply(w, uid, markerID, body)
+}
+
+func postDepartmentResponse(w http.ResponseWriter, r *http.Request, uid, markerID int, body models.CreateOfficialResponseRequest) {
+	markerDept, err := repositories.MarkerDepartmentID(markerID)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Marker not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	// представитель отвечает только от своего ведомства и только по назначенным ему меткам
+	if !allowModeratorOrAdmin(r) {
+		deptID, ok := requireAssignedDepartment(w, uid, markerDept)
+		if !ok {
+			return
+		}
+		body.DepartmentID = deptID
+	}
+	if body.DepartmentID <= 0 {
+		// по умолчанию отвечает назначенное ведомство метки
+		body.DepartmentID = markerDept
+	}
+	if body.DepartmentID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "department_id required")
+		return
+	}
+	body.ResponseType = strings.TrimSpace(body.ResponseType)
+	if body.ResponseType == "" {
+		body.ResponseType = repositories.OfficialResponseInfoRequested
+	}
+	if !repositories.ValidOfficialResponseType(body.ResponseType) {
+		respondWithError(w, http.StatusBadRequest, "Invalid response_type")
+		return
+	}
+	var ok bool
+	if body.PlannedDate, body.ActualDate, ok = normalizeResponseDates(body.PlannedDate, body.ActualDate); !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid date")
+		return
+	}
+	id, err := repositories.CreateOfficialResponse(markerID, models.OfficialAuthorDepartment, body.DepartmentID, uid, body)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
+	paused := false
+	if body.DepartmentID == markerDept {
+		paused = applyOfficialResponseSLA(markerID, id, body.ResponseType, uid)
+	}
 	notifyOfficialResponse(markerID, body.DepartmentID)
-	row, _ := fetchOfficialResponse(markerID)
-	respondWithJSON(w, http.StatusCreated, map[string]interface{}{"status": "success", "response": row})
+	row, _ := repositories.GetOfficialResponse(markerID, id)
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{"status": "success", "response": row, "sla_paused": paused})
 }
 
+// postCitizenReply — ответ заявителя в переписке; снимает паузу SLA, если ведомство ждало уточнений.
+func postCitizenReply(w http.ResponseWriter, uid, markerID int, body models.CreateOfficialResponseRequest) {
+	if body.ParentID == nil || *body.ParentID <= 0 {
+		if last, err := repositories.LatestDepartmentResponse(markerID); err == nil {
+			body.ParentID = &last.ID
+		}
+	}
+	body.ResponseType = repositories.OfficialResponseCitizenReply
+	body.PlannedDate, body.ActualDate = "", ""
+	id, err := repositories.CreateOfficialResponse(markerID, models.OfficialAuthorCitizen, 0, uid, body)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	actor := uid
+	resumed, err := repositories.ResumeMarkerSLA(markerID, &actor)
+	if err != nil {
+		log.Printf("sla resume marker %d: %v", markerID, err)
+	}
+	notifyBody := "Заявитель ответил в переписке по обращению."
+	if resumed > 0 {
+		notifyBody += " Срок решения снова идёт."
+	}
+	parentDept := 0
+	if body.ParentID != nil {
+		if parent, err := repositories.GetOfficialResponse(markerID, *body.ParentID); err == nil && parent.DepartmentID != nil {
+			parentDept = *parent.DepartmentID
+		}
+	}
+	if parentDept > 0 {
+		services.NotifyDepartment(parentDept, markerID, "official_reply", "Ответ заявителя", notifyBody)
+	} else {
+		services.NotifyDepartmentReps(markerID, "official_reply", "Ответ заявителя", notifyBody)
+	}
+	row, _ := repositories.GetOfficialResponse(markerID, id)
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"status": "success", "response": row, "sla_resumed": resumed > 0,
+	})
+}
+
+// PutOfficialResponseHandler — правка последнего ответа ведомства (прежний API с одной записью на метку).
 func PutOfficialResponseHandler(w http.ResponseWriter, r *http.Request) {
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	last, err := repositories.LatestDepartmentResponse(markerID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Response not found")
+		return
+	}
+	updateOfficialResponse(w, r, markerID, last.ID)
+}
+
+// PutOfficialResponseEntryHandler PUT /api/markers/{id}/official-responses/{responseId} — правка одной записи.
+func PutOfficialResponseEntryHandler(w http.ResponseWriter, r *http.Request) {
+	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || markerID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+		return
+	}
+	responseID, err := strconv.Atoi(mux.Vars(r)["responseId"])
+	if err != nil || responseID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid response id")
+		return
+	}
+	updateOfficialResponse(w, r, markerID, responseID)
+}
+
+func updateOfficialResponse(w http.ResponseWriter, r *http.Request, markerID, responseID int) {
 	uid, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
@@ -115,42 +256,82 @@ func PutOfficialResponseHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusForbidden, "Forbidden")
 		return
 	}
-	markerID, err := strconv.Atoi(mux.Vars(r)["id"])
-	if err != nil || markerID <= 0 {
-		respondWithError(w, http.StatusBadRequest, "Invalid marker id")
+	cur, err := repositories.GetOfficialResponse(markerID, responseID)
+	if err != nil || cur.AuthorKind != models.OfficialAuthorDepartment {
+		respondWithError(w, http.StatusNotFound, "Response not found")
+		return
+	}
+	markerDept, err := repositories.MarkerDepartmentID(markerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
+	if !allowModeratorOrAdmin(r) {
+		deptID, ok := requireAssignedDepartment(w, uid, markerDept)
+		if !ok {
+			return
+		}
+		if cur.DepartmentID == nil || *cur.DepartmentID != deptID {
+			respondWithError(w, http.StatusForbidden, "Ответ другого ведомства")
+			return
+		}
+	}
 	var body struct {
-		ResponseText string `json:"response_text"`
-		ResponseType string `json:"response_type"`
-		PlannedDate  string `json:"planned_date"`
-		ActualDate   string `json:"actual_date"`
+		ResponseText string   `json:"response_text"`
+		ResponseType string   `json:"response_type"`
+		PlannedDate  string   `json:"planned_date"`
+		ActualDate   string   `json:"actual_date"`
+		Attachments  []string `json:"attachments"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	res, err := database.DB.Exec(`
-		UPDATE official_responses SET
-			response_text = COALESCE(NULLIF($2,''), response_text),
-			response_type = COALESCE(NULLIF($3,''), response_type),
-			planned_date = CASE WHEN $4 = '' THEN planned_date ELSE $4::date END,
-			actual_date = CASE WHEN $5 = '' THEN actual_date ELSE $5::date END,
-			updated_at = NOW()
-		WHERE marker_id = $1`,
-		markerID, body.ResponseText, body.ResponseType, body.PlannedDate, body.ActualDate,
-	)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Database error")
+	rtype := strings.TrimSpace(body.ResponseType)
+	if rtype != "" && !repositories.ValidOfficialResponseType(rtype) {
+		respondWithError(w, http.StatusBadRequest, "Invalid response_type")
 		return
 	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		respondWithError(w, http.StatusNotFound, "Response not found")
+	attachments, err := repositories.NormalizeAttachments(body.Attachments)
+	if err != nil || len(attachments)+len(cur.Attachments) > repositories.MaxOfficialResponseAttachments {
+		respondWithError(w, http.StatusBadRequest, repositories.ErrInvalidAttachment.Error())
 		return
 	}
-	row, _ := fetchOfficialResponse(markerID)
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{"status": "success", "response": row})
+	if body.PlannedDate, body.ActualDate, ok = normalizeResponseDates(body.PlannedDate, body.ActualDate); !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid date")
+		return
+	}
+	if err := repositories.UpdateOfficialResponse(markerID, responseID, body.ResponseText, rtype,
+		body.PlannedDate, body.ActualDate, attachments); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Response not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	row, _ := repositories.GetOfficialResponse(markerID, responseID)
+	resp := map[string]interface{}{"status": "success", "response": row}
+	// паузой срока управляет только назначенное ведомство
+	if rtype != "" && cur.DepartmentID != nil && *cur.DepartmentID == markerDept {
+		resp["sla_paused"] = applyOfficialResponseSLA(markerID, responseID, rtype, uid)
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// normalizeResponseDates — planned_date и actual_date в формате ГГГГ-ММ-ДД; пустая строка — без даты.
+// false — дата не разбирается (иначе запрос упал бы в БД на приведении к date).
+func normalizeResponseDates(planned, actual string) (string, string, bool) {
+	planned, actual = strings.TrimSpace(planned), strings.TrimSpace(actual)
+	for _, d := range []string{planned, actual} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return planned, actual, false
+		}
+	}
+	return planned, actual, true
 }
 
 func canPostOfficialResponse(r *http.Request, uid int) bool {
@@ -162,31 +343,23 @@ func canPostOfficialResponse(r *http.Request, uid int) bool {
 	return rep
 }
 
-func fetchOfficialResponse(markerID int) (map[string]interface{}, error) {
-	var id, deptID int
-	var text, rtype string
-	var planned, actual *time.Time
-	var created, updated time.Time
-	var deptName, deptIcon string
-	err := database.DB.QueryRow(`
-		SELECT o.id, o.department_id, o.response_text, o.response_type,
-		       o.planned_date, o.actual_date, o.created_at, o.updated_at,
-		       d.name_ru, COALESCE(d.icon,'')
-		FROM official_responses o
-		JOIN departments d ON d.id = o.department_id
-		WHERE o.marker_id = $1
-		ORDER BY o.updated_at DESC LIMIT 1`, markerID,
-	).Scan(&id, &deptID, &text, &rtype, &planned, &actual, &created, &updated, &deptName, &deptIcon)
+// requireAssignedDepartment — ведомство представителя; метка должна быть назначена именно ему.
+// Представитель без ведомства официальных ответов не пишет.
+func requireAssignedDepartment(w http.ResponseWriter, uid, markerDept int) (int, bool) {
+	deptID, _, err := repositories.UserDepartment(uid)
 	if err != nil {
-		return nil, err
-	}
-	return map[string]interface{}{
-		"id": id, "marker_id": markerID, "department_id": deptID,
-		"department_name": deptName, "department_icon": deptIcon,
-		"response_text": text, "response_type": rtype,
-		"planned_date": planned, "actual_date": actual,
-		"created_at": created, "updated_at": updated,
-	}, nil
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return 0, false
+	}
+	if deptID <= 0 {
+		respondWithError(w, http.StatusForbidden, "Представитель не привязан к ведомству")
+		return 0, false
+	}
+	if deptID != markerDept {
+		respondWithError(w, http.StatusForbidden, "Обращение назначено другому ведомству")
+		return 0, false
+	}
+	return deptID, true
 }
 
 func notifyOfficialResponse(markerID, deptID int) {
@@ -204,3 +377,19 @@ func notifyOfficialResponse(markerID, deptID int) {
 	markerIDPtr := markerID
 	services.AwardPoints(ownerID, "official_response", services.PointsOfficialReply, "Официальный ответ ведомства", &markerIDPtr)
 }
+
+// applyOfficialResponseSLA: запрос уточнений ставит срок решения на паузу, любой другой ответ ведомства её снимает.
+func applyOfficialResponseSLA(markerID, responseID int, responseType string, actorID int) bool {
+	actor := actorID
+	if responseType == repositories.OfficialResponseInfoRequested {
+		paused, err := repositories.PauseMarkerSLA(markerID, responseID, &actor)
+		if err != nil {
+			log.Printf("sla pause marker %d: %v", markerID, err)
+		}
+		return paused
+	}
+	if _, err := repositories.ResumeMarkerSLA(markerID, &actor); err != nil {
+		log.Printf("sla resume marker %d: %v", markerID, err)
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeResponseDates(t *testing.T) {
+	cases := []struct {
+		planned, actual string
+		ok              bool
+	}{
+		{"", "", true},
+		{"2026-05-01", "", true},
+		{" 2026-05-01 ", "2026-05-20", true},
+		{"2026-13-01", "", false},
+		{"", "01.05.2026", false},
+		{"2026-02-30", "", false},
+		{"tomorrow", "2026-05-20", false},
+	}
+	for _, tc := range cases {
+		planned, actual, ok := normalizeResponseDates(tc.planned, tc.actual)
+		if ok != tc.ok {
+			t.Errorf("normalizeResponseDates(%q, %q) ok = %v, want %v", tc.planned, tc.actual, ok, tc.ok)
+		}
+		if ok && (planned != strings.TrimSpace(tc.planned) || actual != strings.TrimSpace(tc.actual)) {
+			t.Errorf("normalizeResponseDates(%q, %q) = %q, %q", tc.planned, tc.actual, planned, actual)
+		}
+	}
+}
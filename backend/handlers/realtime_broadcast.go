@@ -1,11 +1,24 @@
 package handlers
 
-import "backend/realtime"
+import (
+	"backend/models"
+	"backend/realtime"
+	"backend/repositories"
+)
 
-func broadcastMarkerCreated(marker interface{}) {
+func broadcastMarkerCreated(marker *models.Marker) {
 	realtime.Broadcast(realtime.Event{
 		Type:    realtime.EventMarkerCreated,
 		Payload: marker,
+		Route: &realtime.Route{
+			MarkerID:    marker.ID,
+			HasLocation: true,
+			Lat:         marker.Latitude,
+			Lng:         marker.Longitude,
+			DomainKey:   marker.DomainKey,
+			Private:     !repositories.IsPublicMarkerStatus(marker.Status),
+			OwnerID:     marker.UserID,
+		},
 	})
 }
 
@@ -17,9 +30,42 @@ func broadcastMarkerUpdated(markerID int, fields map[string]interface{}) {
 	realtime.Broadcast(realtime.Event{
 		Type:    realtime.EventMarkerUpdated,
 		Payload: payload,
+		Route:   markerRoute(markerID),
 	})
 }
 
+// broadcastMarkerStatusChanged — смена статуса. Если метка ушла с публичной карты, событие
+// (в нём только статус) получают все подписчики, чтобы убрать её у себя.
+func broadcastMarkerStatusChanged(markerID int, oldStatus string, fields map[string]interface{}) {
+	payload := map[string]interface{}{"id": markerID}
+	for k, v := range fields {
+		payload[k] = v
+	}
+	route := markerRoute(markerID)
+	if repositories.IsPublicMarkerStatus(oldStatus) {
+		route.Private = false
+	}
+	realtime.Broadcast(realtime.Event{
+		Type:    realtime.EventMarkerUpdated,
+		Payload: payload,
+		Route:   route,
+	})
+}
+
+// markerRoute — признаки метки для подписок; удалённую метку маршрутизируем только по id.
+// События неопубликованной метки (на проверке, отклонена) получают только модераторы и автор.
+func markerRoute(markerID int) *realtime.Route {
+	route := &realtime.Route{MarkerID: markerID}
+	if info, err := repositories.GetMarkerRouteInfo(markerID); err == nil {
+		route.HasLocation = true
+		route.Lat, route.Lng = info.Lat, info.Lng
+		route.DomainKey = info.DomainKey
+		route.Private = !repositories.IsPublicMarkerStatus(info.Status)
+		route.OwnerID = info.OwnerID
+	}
+	return route
+}
+
 func broadcastUserNotification(userID int, notification interface{}) {
 	realtime.BroadcastToUser(userID, realtime.Event{
 		Type:    realtime.EventNotification,
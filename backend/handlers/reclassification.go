@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/middleware"
+	"backend/repositories"
+)
+
+const maxReclassifyBulk = 500
+
+func actorPtrFromRequest(r *http.Request) *int {
+	uid, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok || uid <= 0 {
+		return nil
+	}
+	return &uid
+}
+
+// EnqueueReclassificationHandler POST /api/moderation/reclassify — поставить метки в очередь повторной классификации.
+func EnqueueReclassificationHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	var body struct {
+		repositories.ReclassifyScope
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if len(body.MarkerIDs) > maxReclassifyBulk {
+		respondWithError(w, http.StatusBadRequest, "Too many marker_ids")
+		return
+	}
+	actor := actorPtrFromRequest(r)
+	n, err := repositories.EnqueueReclassification(body.ReclassifyScope, body.Reason, actor)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	repositories.InsertAuditLog(actor, "reclassification_enqueue", "marker", nil, map[string]interface{}{
+		"scope": body.ReclassifyScope, "reason": body.Reason, "enqueued": n,
+	})
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{"status": "success", "enqueued": n})
+}
+
+// ReclassificationStatusHandler GET /api/moderation/reclassify/status — размер очереди по статусам.
+func ReclassificationStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	stats, err := repositories.ReclassificationQueueStats()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"jobs": stats})
+}
+
+// ListReclassificationSuggestionsHandler GET /api/moderation/reclassify/suggestions?domain_key=&min_confidence=&page=
+func ListReclassificationSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	pageSize, _ := strconv.Atoi(q.Get("page_size"))
+	minConf, _ := strconv.ParseFloat(q.Get("min_confidence"), 64)
+	list, total, err := repositories.ListReclassificationSuggestions(strings.TrimSpace(q.Get("domain_key")), minConf, page, pageSize)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"suggestions": list,
+		"total":       total,
+		"count":       len(list),
+	})
+}
+
+func decodeReclassifyIDs(w http.ResponseWriter, r *http.Request) ([]int, bool) {
+	var body struct {
+		MarkerIDs []int `json:"marker_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return nil, false
+	}
+	if len(body.MarkerIDs) == 0 || len(body.MarkerIDs) > maxReclassifyBulk {
+		respondWithError(w, http.StatusBadRequest, "marker_ids: 1..500 ids required")
+		return nil, false
+	}
+	return body.MarkerIDs, true
+}
+
+// AcceptReclassificationHandler POST /api/moderation/reclassify/accept — принять предложенные категории.
+func AcceptReclassificationHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	ids, ok := decodeReclassifyIDs(w, r)
+	if !ok {
+		return
+	}
+	actor := actorPtrFromRequest(r)
+	res, err := repositories.AcceptReclassificationSuggestions(ids, actor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	repositories.InsertAuditLog(actor, "reclassification_accept", "marker", nil, map[string]interface{}{
+		"marker_ids": ids, "accepted": len(res.Accepted), "stale_ids": res.Stale,
+	})
+	if len(res.Accepted) > 0 {
+		broadcastMarkersReclassified(res.Accepted)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":       "success",
+		"accepted":     len(res.Accepted),
+		"accepted_ids": res.Accepted,
+		// устаревшие предложения сняты — категория этих меток не менялась
+		"stale_ids": res.Stale,
+	})
+}
+
+// DismissReclassificationHandler POST /api/moderation/reclassify/dismiss — оставить текущие категории.
+func DismissReclassificationHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireModerator(w, r) {
+		return
+	}
+	ids, ok := decodeReclassifyIDs(w, r)
+	if !ok {
+		return
+	}
+	n, err := repositories.DismissReclassificationSuggestions(ids)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"status": "success", "dismissed": n})
+}
+
+func broadcastMarkersReclassified(ids []int) {
+	repo := repositories.NewMarkerRepository()
+	for _, id := range ids {
+		if m, err := repo.GetByID(id); err == nil && m != nil {
+			broadcastMarkerUpdated(id, map[string]interface{}{
+				"domain_key": m.DomainKey, "group_key": m.GroupKey, "issue_key": m.IssueKey,
+			})
+		}
+	}
+}
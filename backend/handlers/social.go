@@ -23,6 +23,21 @@ func NearbyMarkersHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "lat and lng required")
 		return
 	}
+	// с text — проверка на дубль перед созданием: кандидаты с оценкой похожести
+	if text := strings.TrimSpace(r.URL.Query().Get("text")); text != "" {
+		candidates, settings, err := repositories.FindDuplicateCandidates(text, r.URL.Query().Get("domain_key"), lat, lng, 10)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"nearby":    candidates,
+			"count":     len(candidates),
+			"threshold": settings.Threshold,
+			"radius_m":  settings.RadiusM,
+		})
+		return
+	}
 	list, err := repositories.NewSupportRepository().FindNearby(lat, lng, radius, 10)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Database error")
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/realtime"
+)
+
+// sseHeartbeat — комментарий-пинг, чтобы прокси не закрывали «молчащее» соединение.
+const sseHeartbeat = 25 * time.Second
+
+// EventsStreamHandler GET /api/events — те же realtime-события, что и /api/ws, через Server-Sent Events.
+// Темы задаются в query: bbox=sw_lat,sw_lng,ne_lat,ne_lng, markers=1,2, domains=roads,parks, moderation=1.
+// Продолжение после обрыва — заголовок Last-Event-ID (EventSource шлёт его сам) или ?since=<seq>.
+func EventsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := realtimeClaims(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	client := realtime.NewClient(claims.UserID, claims.IsModerator || claims.IsAdmin, nil)
+	if err := subscribeFromQuery(client, r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	since := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if since == "" {
+		since = r.URL.Query().Get("since")
+	}
+	client.ResumeFrom, _ = strconv.ParseInt(since, 10, 64)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// nginx не должен буферизовать поток
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	realtime.RegisterClient(client)
+	defer realtime.UnregisterClient(client)
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-client.Send:
+			if !ok {
+				// hub отключил клиента (переполнен буфер) — EventSource переподключится с Last-Event-ID
+				return
+			}
+			if writeSSEEvent(w, msg) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSEEvent — id: seq (если есть), event: тип события, data: JSON целиком, как в WebSocket.
+func writeSSEEvent(w http.ResponseWriter, msg []byte) error {
+	var head struct {
+		Seq  int64  `json:"seq"`
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(msg, &head)
+	var b strings.Builder
+	if head.Seq > 0 {
+		fmt.Fprintf(&b, "id: %d\n", head.Seq)
+	}
+	if head.Type != "" {
+		fmt.Fprintf(&b, "event: %s\n", head.Type)
+	}
+	fmt.Fprintf(&b, "data: %s\n\n", msg)
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+func subscribeFromQuery(c *realtime.Client, r *http.Request) error {
+	q := r.URL.Query()
+	if raw := strings.TrimSpace(q.Get("bbox")); raw != "" {
+		parts := strings.Split(raw, ",")
+		if len(parts) != 4 {
+			return fmt.Errorf("bbox: expected sw_lat,sw_lng,ne_lat,ne_lng")
+		}
+		var v [4]float64
+		for i, p := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return fmt.Errorf("bbox: %v", err)
+			}
+			v[i] = f
+		}
+		bbox := &realtime.BBox{SWLat: v[0], SWLng: v[1], NELat: v[2], NELng: v[3]}
+		if err := c.Subscribe(realtime.TopicViewport, bbox, nil, nil); err != nil {
+			return err
+		}
+	}
+	if raw := strings.TrimSpace(q.Get("markers")); raw != "" {
+		var ids []int
+		for _, p := range strings.Split(raw, ",") {
+			if id, err := strconv.Atoi(strings.TrimSpace(p)); err == nil && id > 0 {
+				ids = append(ids, id)
+			}
+		}
+		if err := c.Subscribe(realtime.TopicMarkers, nil, ids, nil); err != nil {
+			return err
+		}
+	}
+	if raw := strings.TrimSpace(q.Get("domains")); raw != "" {
+		if err := c.Subscribe(realtime.TopicDomains, nil, nil, strings.Split(raw, ",")); err != nil {
+			return err
+		}
+	}
+	if q.Get("moderation") == "1" || strings.EqualFold(q.Get("moderation"), "true") {
+		if err := c.Subscribe(realtime.TopicModeration, nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
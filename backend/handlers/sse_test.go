@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/middleware"
+	"backend/models"
+	"backend/realtime"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestWriteSSEEvent(t *testing.T) {
+	w := httptest.NewRecorder()
+	msg := `{"seq":42,"type":"marker_updated","payload":{"id":7},"ts":1}`
+	if err := writeSSEEvent(w, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	want := "id: 42\nevent: marker_updated\ndata: " + msg + "\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	w = httptest.NewRecorder()
+	_ = writeSSEEvent(w, []byte(`{"type":"moderation_presence","payload":{}}`))
+	if strings.HasPrefix(w.Body.String(), "id:") {
+		t.Error("events without seq must not carry an id")
+	}
+}
+
+func TestSubscribeFromQuery(t *testing.T) {
+	c := realtime.NewClient(1, false, nil)
+	r := httptest.NewRequest("GET", "/api/events?bbox=55.5,37.3,56,37.9&markers=1,x,2&domains=roads", nil)
+	if err := subscribeFromQuery(c, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{"bbox=1,2,3", "bbox=a,b,c,d", "moderation=1"} {
+		c := realtime.NewClient(1, false, nil)
+		if err := subscribeFromQuery(c, httptest.NewRequest("GET", "/api/events?"+q, nil)); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
+
+func TestEventsStreamHidesUnpublishedMarkers(t *testing.T) {
+	realtime.Start(nil)
+	srv := httptest.NewServer(http.HandlerFunc(EventsStreamHandler))
+	defer srv.Close()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &middleware.Claims{
+		UserID:         5,
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	}).SignedString(middleware.JwtKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// без тем клиент получает все публичные события
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"?token="+token, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data := make(chan string, 16)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if line := sc.Text(); strings.HasPrefix(line, "data: ") {
+				data <- strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	// клиент регистрируется в hub асинхронно — шлём пары событий, пока не дойдёт первое;
+	// события приходят по порядку, так что первой должна оказаться опубликованная метка
+	tick := time.NewTicker(20 * time.Millisecond)
+	defer tick.Stop()
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case msg := <-data:
+			var ev struct {
+				Type    string `json:"type"`
+				Payload struct {
+					ID int `json:"id"`
+				} `json:"payload"`
+			}
+			if err := json.Unmarshal([]byte(msg), &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Type != realtime.EventMarkerCreated || ev.Payload.ID != 2 {
+				t.Fatalf("non-moderator got %s for marker %d, want only published marker 2", ev.Type, ev.Payload.ID)
+			}
+			return
+		case <-tick.C:
+			broadcastMarkerCreated(&models.Marker{ID: 1, UserID: 7, Status: "pending", UserEmail: "author@example.com"})
+			broadcastMarkerCreated(&models.Marker{ID: 2, UserID: 7, Status: "approved"})
+		case <-deadline:
+			t.Fatal("no events received")
+		}
+	}
+}
@@ -10,11 +10,11 @@ func PublicMapStatsHandler(w http.ResponseWriter, r *http.Request) {
 	var active, resolved, total int
 	err := database.DB.QueryRow(`
 		SELECT
-			COUNT(*) FILTER (WHERE LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending')) IN ('approved', 'in_progress'))::int,
+			COUNT(*) FILTER (WHERE LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened'))::int,
 			COUNT(*) FILTER (WHERE LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending')) = 'resolved')::int,
 			COUNT(*)::int
 		FROM markers
-		WHERE LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending')) IN ('approved', 'in_progress', 'resolved')
+		WHERE LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened', 'resolved')
 	`).Scan(&active, &resolved, &total)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Database error")
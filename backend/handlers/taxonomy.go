@@ -3,7 +3,10 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"backend/middleware"
@@ -54,6 +57,30 @@ func AdminListClassificationsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// AdminClassificationQualityHandler GET /api/admin/classification-quality?days=&threshold=&limit= —
+// точность ответа ИИ относительно итоговой категории модератора, матрица ошибок, калибровка и очередь низкой уверенности.
+func AdminClassificationQualityHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	q := r.URL.Query()
+	days := 90
+	if s := q.Get("days"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil {
+			days = v
+		}
+	}
+	threshold, _ := strconv.ParseFloat(q.Get("threshold"), 64)
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	quality, err := repositories.GetClassificationQuality(days, threshold, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quality)
+}
+
 func AdminCreateClassificationHandler(w http.ResponseWriter, r *http.Request) {
 	if !allowModeratorOrAdmin(r) {
 		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
@@ -68,6 +95,9 @@ func AdminCreateClassificationHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	recordTaxonomyVersion(r, "domain_created:"+strings.TrimSpace(req.Key))
+	// новое направление может лучше подойти уже открытым обращениям
+	enqueueTaxonomyReclassification(r, repositories.ReclassifyScope{OpenOnly: true}, "domain_created:"+strings.TrimSpace(req.Key))
 	tax, _ := repositories.ListTaxonomy()
 	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
 		"status":   "success",
@@ -98,6 +128,10 @@ func AdminPatchClassificationHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	recordTaxonomyVersion(r, "domain_updated:"+key)
+	if req.TrainingPhrasesRu != nil {
+		enqueueTaxonomyReclassification(r, repositories.ReclassifyScope{DomainKey: key}, "domain_updated:"+key)
+	}
 	tax, _ := repositories.ListTaxonomy()
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"status":   "success",
@@ -119,6 +153,7 @@ func AdminReorderClassificationsHandler(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	recordTaxonomyVersion(r, "domains_reordered")
 	tax, _ := repositories.ListTaxonomy()
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"status":   "success",
@@ -126,19 +161,61 @@ func AdminReorderClassificationsHandler(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// AdminDeleteClassificationHandler DELETE /api/admin/classifications/{key}?target_domain_key=other | ?orphan=true
+// Если у направления есть обращения, нужно указать, куда их перенести, или явно оставить без категории.
 func AdminDeleteClassificationHandler(w http.ResponseWriter, r *http.Request) {
 	if !allowModeratorOrAdmin(r) {
 		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
 		return
 	}
 	key := strings.TrimSpace(mux.Vars(r)["key"])
-	if err := repositories.DeleteClassification(key); err != nil {
-		if err == sql.ErrNoRows {
+	q := r.URL.Query()
+	orphan := q.Get("orphan")
+	opt := repositories.DomainDeletion{
+		TargetDomainKey: q.Get("target_domain_key"),
+		Orphan:          orphan == "1" || strings.EqualFold(orphan, "true"),
+	}
+	actor := actorPtrFromRequest(r)
+	moved, err := repositories.DeleteClassification(key, opt, actor)
+	if err != nil {
+		var inUse *repositories.DomainInUseError
+		switch {
+		case err == sql.ErrNoRows:
 			respondWithError(w, http.StatusNotFound, "Classification not found")
-			return
+		case errors.As(err, &inUse):
+			respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+				"error":   err.Error(),
+				"markers": inUse.Markers,
+			})
+		case errors.Is(err, repositories.ErrUnknownDomain):
+			respondWithError(w, http.StatusBadRequest, "Unknown target_domain_key")
+		default:
+			respondWithError(w, http.StatusBadRequest, err.Error())
 		}
-		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{"status": "success"})
+	recordTaxonomyVersion(r, "domain_deleted:"+key)
+	repositories.InsertAuditLog(actor, "classification_delete", "taxonomy", nil, map[string]interface{}{
+		"domain_key": key, "target_domain_key": strings.TrimSpace(opt.TargetDomainKey),
+		"orphan": opt.Orphan, "markers_moved": len(moved),
+	})
+	if len(moved) > 0 {
+		broadcastMarkersReclassified(moved)
+		// группа и проблема у перенесённых сброшены, у оставленных без категории нет и направления —
+		// классификатор предложит новую категорию и тем, и другим
+		enqueueTaxonomyReclassification(r, repositories.ReclassifyScope{MarkerIDs: moved}, "domain_deleted:"+key)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        "success",
+		"markers_moved": len(moved),
+		"marker_ids":    moved,
+	})
+}
+
+func enqueueTaxonomyReclassification(r *http.Request, scope repositories.ReclassifyScope, reason string) {
+	if n, err := repositories.EnqueueReclassification(scope, reason, actorPtrFromRequest(r)); err != nil {
+		log.Printf("reclassify enqueue (%s): %v", reason, err)
+	} else if n > 0 {
+		log.Printf("reclassify enqueue (%s): %d markers", reason, n)
+	}
 }
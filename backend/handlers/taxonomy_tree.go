@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"backend/models"
+	"backend/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+// Группы и проблемы внутри направления; ответы совпадают с обработчиками направлений — возвращается вся таксономия.
+
+func taxonomyTreeVars(r *http.Request) (domain, group, issue string) {
+	v := mux.Vars(r)
+	return strings.TrimSpace(v["key"]), strings.TrimSpace(v["group"]), strings.TrimSpace(v["issue"])
+}
+
+func respondWithTaxonomy(w http.ResponseWriter, code int) {
+	tax, _ := repositories.ListTaxonomy()
+	respondWithJSON(w, code, map[string]interface{}{
+		"status":   "success",
+		"taxonomy": tax,
+	})
+}
+
+func respondWithTaxonomyError(w http.ResponseWriter, err error, notFound string) {
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, notFound)
+		return
+	}
+	respondWithError(w, http.StatusBadRequest, err.Error())
+}
+
+// AdminListTaxonomyGroupsHandler GET /api/admin/classifications/{key}/groups
+func AdminListTaxonomyGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, _, _ := taxonomyTreeVars(r)
+	tax, err := repositories.ListTaxonomy()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	for _, d := range tax.Domains {
+		if d.Key == domain {
+			groups := d.Groups
+			if groups == nil {
+				groups = []models.TaxonomyGroup{}
+			}
+			respondWithJSON(w, http.StatusOK, map[string]interface{}{
+				"status":     "success",
+				"domain_key": domain,
+				"groups":     groups,
+			})
+			return
+		}
+	}
+	respondWithError(w, http.StatusNotFound, "Classification not found")
+}
+
+// AdminCreateTaxonomyGroupHandler POST /api/admin/classifications/{key}/groups
+func AdminCreateTaxonomyGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, _, _ := taxonomyTreeVars(r)
+	var req models.CreateTaxonomyNodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := repositories.CreateTaxonomyGroup(domain, req); err != nil {
+		respondWithTaxonomyError(w, err, "Classification not found")
+		return
+	}
+	recordTaxonomyVersion(r, "group_created:"+domain+"/"+strings.TrimSpace(req.Key))
+	respondWithTaxonomy(w, http.StatusCreated)
+}
+
+// AdminPatchTaxonomyGroupHandler PATCH /api/admin/classifications/{key}/groups/{group}
+func AdminPatchTaxonomyGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, group, _ := taxonomyTreeVars(r)
+	var req models.UpdateTaxonomyNodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := repositories.UpdateTaxonomyGroup(domain, group, req); err != nil {
+		respondWithTaxonomyError(w, err, "Group not found")
+		return
+	}
+	recordTaxonomyVersion(r, "group_updated:"+domain+"/"+group)
+	respondWithTaxonomy(w, http.StatusOK)
+}
+
+// AdminDeleteTaxonomyGroupHandler DELETE /api/admin/classifications/{key}/groups/{group}
+func AdminDeleteTaxonomyGroupHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, group, _ := taxonomyTreeVars(r)
+	if err := repositories.DeleteTaxonomyGroup(domain, group); err != nil {
+		respondWithTaxonomyError(w, err, "Group not found")
+		return
+	}
+	recordTaxonomyVersion(r, "group_deleted:"+domain+"/"+group)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"status": "success"})
+}
+
+// AdminReorderTaxonomyGroupsHandler POST /api/admin/classifications/{key}/groups/reorder
+func AdminReorderTaxonomyGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, _, _ := taxonomyTreeVars(r)
+	var req models.ReorderClassificationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := repositories.ReorderTaxonomyGroups(domain, req.Keys); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recordTaxonomyVersion(r, "groups_reordered:"+domain)
+	respondWithTaxonomy(w, http.StatusOK)
+}
+
+// AdminCreateTaxonomyIssueHandler POST /api/admin/classifications/{key}/groups/{group}/issues
+func AdminCreateTaxonomyIssueHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, group, _ := taxonomyTreeVars(r)
+	var req models.CreateTaxonomyNodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := repositories.CreateTaxonomyIssue(domain, group, req); err != nil {
+		respondWithTaxonomyError(w, err, "Group not found")
+		return
+	}
+	recordTaxonomyVersion(r, "issue_created:"+domain+"/"+group+"/"+strings.TrimSpace(req.Key))
+	respondWithTaxonomy(w, http.StatusCreated)
+}
+
+// AdminPatchTaxonomyIssueHandler PATCH /api/admin/classifications/{key}/groups/{group}/issues/{issue}
+func AdminPatchTaxonomyIssueHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, group, issue := taxonomyTreeVars(r)
+	var req models.UpdateTaxonomyNodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := repositories.UpdateTaxonomyIssue(domain, group, issue, req); err != nil {
+		respondWithTaxonomyError(w, err, "Issue not found")
+		return
+	}
+	recordTaxonomyVersion(r, "issue_updated:"+domain+"/"+group+"/"+issue)
+	respondWithTaxonomy(w, http.StatusOK)
+}
+
+// AdminDeleteTaxonomyIssueHandler DELETE /api/admin/classifications/{key}/groups/{group}/issues/{issue}
+func AdminDeleteTaxonomyIssueHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, group, issue := taxonomyTreeVars(r)
+	if err := repositories.DeleteTaxonomyIssue(domain, group, issue); err != nil {
+		respondWithTaxonomyError(w, err, "Issue not found")
+		return
+	}
+	recordTaxonomyVersion(r, "issue_deleted:"+domain+"/"+group+"/"+issue)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"status": "success"})
+}
+
+// AdminReorderTaxonomyIssuesHandler POST /api/admin/classifications/{key}/groups/{group}/issues/reorder
+func AdminReorderTaxonomyIssuesHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	domain, group, _ := taxonomyTreeVars(r)
+	var req models.ReorderClassificationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := repositories.ReorderTaxonomyIssues(domain, group, req.Keys); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recordTaxonomyVersion(r, "issues_reordered:"+domain+"/"+group)
+	respondWithTaxonomy(w, http.StatusOK)
+}
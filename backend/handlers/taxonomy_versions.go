@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/models"
+	"backend/repositories"
+	"backend/services"
+
+	"github.com/gorilla/mux"
+)
+
+const maxTaxonomyImportBytes = 2 << 20
+
+// recordTaxonomyVersion — после каждого изменения таксономии сохраняем снимок (если что-то поменялось).
+func recordTaxonomyVersion(r *http.Request, reason string) {
+	if _, err := repositories.SnapshotTaxonomy(actorPtrFromRequest(r), reason); err != nil {
+		log.Printf("taxonomy snapshot (%s): %v", reason, err)
+	}
+}
+
+// AdminListTaxonomyVersionsHandler GET /api/admin/taxonomy/versions?limit=
+func AdminListTaxonomyVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	list, err := repositories.ListTaxonomyVersions(limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"versions": list, "count": len(list)})
+}
+
+// AdminGetTaxonomyVersionHandler GET /api/admin/taxonomy/versions/{version} — снимок целиком.
+func AdminGetTaxonomyVersionHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	version, _ := strconv.Atoi(mux.Vars(r)["version"])
+	v, err := repositories.GetTaxonomyVersion(version)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Version not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, v)
+}
+
+// AdminDiffTaxonomyVersionsHandler GET /api/admin/taxonomy/diff?from=3&to=5 — без to сравнение с текущей таксономией.
+func AdminDiffTaxonomyVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	q := r.URL.Query()
+	from, err := strconv.Atoi(q.Get("from"))
+	if err != nil || from <= 0 {
+		respondWithError(w, http.StatusBadRequest, "from: version number required")
+		return
+	}
+	fromV, err := repositories.GetTaxonomyVersion(from)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Version not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	var toTax *models.Taxonomy
+	to := 0
+	if s := strings.TrimSpace(q.Get("to")); s != "" {
+		to, err = strconv.Atoi(s)
+		if err != nil || to <= 0 {
+			respondWithError(w, http.StatusBadRequest, "to: invalid version number")
+			return
+		}
+		toV, err := repositories.GetTaxonomyVersion(to)
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Version not found")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		toTax = toV.Snapshot
+	} else if toTax, err = repositories.ListTaxonomy(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"from": from,
+		"to":   to,
+		"diff": services.DiffTaxonomies(fromV.Snapshot, toTax),
+	})
+}
+
+// AdminRollbackTaxonomyHandler POST /api/admin/taxonomy/versions/{version}/rollback
+func AdminRollbackTaxonomyHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowModeratorOrAdmin(r) {
+		respondWithError(w, http.StatusForbidden, "Moderator or admin only")
+		return
+	}
+	version, _ := strconv.Atoi(mux.Vars(r)["version"])
+	actor := actorPtrFromRequest(r)
+	newVersion, err := repositories.RollbackTaxonomy(version, actor)
+	if err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Version not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	repositories.InsertAuditLog(actor, "taxonomy_rollback", "taxonomy", nil, map[string]interface{}{
+		"version": version, "new_version": newVersion,
+	})
+	tax, _ := repositories.ListTaxonomy()
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":      "success",
+		"new_version": newVersion,
+		"taxonomy":    tax,
+	})
+}
+
+// AdminImportTaxonomyHandler POST /api/admin/classifications/import?dry_run=1 — тело в формате issue-taxonomy.json.
+// Без dry_run план применяется одной транзакцией; удаление категорий, на которые ссылаются обращения, блокирует импорт.
+func AdminImportTaxonomyHandler(w http.ResponseWriter, r *http.Request) {
+	_, isAdmin, ok := adminActorFromDB(r.Context())
+	if !ok || !isAdmin {
+		respondWithError(w, http.StatusForbidden, "Admin only")
+		return
+	}
+	var tax models.Taxonomy
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTaxonomyImportBytes)).Decode(&tax); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run")
+	isDryRun := dryRun == "1" || strings.EqualFold(dryRun, "true")
+	plan, err := services.PlanTaxonomyImport(&tax)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	switch {
+	case len(plan.Errors) > 0:
+		respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"status": "invalid", "dry_run": isDryRun, "plan": plan,
+		})
+		return
+	case isDryRun:
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"status": "dry_run", "dry_run": true, "plan": plan,
+		})
+		return
+	case plan.Blocked:
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"status": "blocked", "dry_run": false, "plan": plan,
+		})
+		return
+	}
+	actor := actorPtrFromRequest(r)
+	version, err := repositories.ReplaceTaxonomy(&tax, actor, "import")
+	if err != nil {
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	repositories.InsertAuditLog(actor, "taxonomy_import", "taxonomy", nil, map[string]interface{}{
+		"added": len(plan.Added), "removed": len(plan.Removed), "changed": len(plan.Changed), "version": version,
+	})
+	if plan.NeedsReclassification() {
+		enqueueTaxonomyReclassification(r, repositories.ReclassifyScope{OpenOnly: true}, "taxonomy_import")
+	}
+	tax2, _ := repositories.ListTaxonomy()
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "success",
+		"dry_run":  false,
+		"plan":     plan,
+		"version":  version,
+		"taxonomy": tax2,
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+const markerTileCacheControl = "public, max-age=60, stale-while-revalidate=300"
+
+// MarkerTileHandler GET /api/tiles/{z}/{x}/{y}.mvt — векторный тайл публичного слоя меток (?layer=active|resolved|all).
+func MarkerTileHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	z, errZ := strconv.Atoi(vars["z"])
+	x, errX := strconv.Atoi(vars["x"])
+	y, errY := strconv.Atoi(vars["y"])
+	if errZ != nil || errX != nil || errY != nil || z < 0 || z > 22 {
+		respondWithError(w, http.StatusBadRequest, "Invalid tile coordinates")
+		return
+	}
+	if n := 1 << uint(z); x < 0 || y < 0 || x >= n || y >= n {
+		respondWithError(w, http.StatusBadRequest, "Invalid tile coordinates")
+		return
+	}
+	layer := strings.TrimSpace(r.URL.Query().Get("layer"))
+	tile, err := repositories.NewMarkerRepository().GetPublicMarkerTile(z, x, y, layer)
+	if err == repositories.ErrPostGISUnavailable {
+		respondWithError(w, http.StatusNotImplemented, "Vector tiles require PostGIS")
+		return
+	}
+	if err != nil {
+		log.Printf("MarkerTileHandler %d/%d/%d: %v", z, x, y, err)
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	sum := sha1.Sum(tile)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("Cache-Control", markerTileCacheControl)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Origin")
+	if match := r.Header.Get("If-None-Match"); match != "" && match == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if len(tile) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Header().Set("Content-Length", strconv.Itoa(len(tile)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(tile)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminListWorkCalendarHandler — праздники и переносы рабочего календаря SLA (?year=2026).
+func AdminListWorkCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	_, isAdmin, ok := adminActorFromDB(r.Context())
+	if !ok || !isAdmin {
+		respondWithError(w, http.StatusForbidden, "Admin only")
+		return
+	}
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+	list, err := repositories.ListWorkCalendarDays(year)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if list == nil {
+		list = []repositories.WorkCalendarDay{}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"days": list, "count": len(list)})
+}
+
+func AdminCreateWorkCalendarDayHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, isAdmin, ok := adminActorFromDB(r.Context())
+	if !ok || !isAdmin {
+		respondWithError(w, http.StatusForbidden, "Admin only")
+		return
+	}
+	var body struct {
+		Day          string `json:"day"`
+		DepartmentID *int   `json:"department_id"`
+		IsWorkday    bool   `json:"is_workday"`
+		NameRu       string `json:"name_ru"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	day, err := time.Parse("2006-01-02", body.Day)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "day must be YYYY-MM-DD")
+		return
+	}
+	id, err := repositories.CreateWorkCalendarDay(day, body.DepartmentID, body.IsWorkday, body.NameRu, actorID)
+	if errors.Is(err, repositories.ErrCalendarDayExists) {
+		respondWithError(w, http.StatusConflict, "Этот день уже есть в календаре")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Не удалось добавить день (проверьте department_id)")
+		return
+	}
+	actor := actorID
+	repositories.InsertAuditLog(&actor, "work_calendar_day_create", "work_calendar_day", &id, map[string]interface{}{
+		"day": body.Day, "department_id": body.DepartmentID, "is_workday": body.IsWorkday, "name_ru": body.NameRu,
+	})
+	respondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"status":     "success",
+		"id":         id,
+		"recomputed": recomputeSLAAfterCalendarChange(actorID),
+	})
+}
+
+func AdminDeleteWorkCalendarDayHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, isAdmin, ok := adminActorFromDB(r.Context())
+	if !ok || !isAdmin {
+		respondWithError(w, http.StatusForbidden, "Admin only")
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid id")
+		return
+	}
+	if err := repositories.DeleteWorkCalendarDay(id); err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Not found")
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	actor := actorID
+	repositories.InsertAuditLog(&actor, "work_calendar_day_delete", "work_calendar_day", &id, nil)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"recomputed": recomputeSLAAfterCalendarChange(actorID),
+	})
+}
+
+// AdminPutDepartmentWeekendHandler — собственные выходные ведомства, ISO-дни (1 = пн … 7 = вс); [] — общий календарь.
+func AdminPutDepartmentWeekendHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, isAdmin, ok := adminActorFromDB(r.Context())
+	if !ok || !isAdmin {
+		respondWithError(w, http.StatusForbidden, "Admin only")
+		return
+	}
+	deptID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || deptID <= 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid department id")
+		return
+	}
+	var body struct {
+		WeekendDays []int `json:"weekend_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := repositories.SetDepartmentWeekendDays(deptID, body.WeekendDays); err == sql.ErrNoRows {
+		respondWithError(w, http.StatusNotFound, "Department not found")
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	actor := actorID
+	repositories.InsertAuditLog(&actor, "department_weekend_update", "department", &deptID, map[string]interface{}{
+		"weekend_days": body.WeekendDays,
+	})
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"recomputed": recomputeSLAAfterCalendarChange(actorID),
+	})
+}
+
+// AdminRecomputeSLAHandler — ручной пересчёт сроков открытых меток по текущему календарю.
+func AdminRecomputeSLAHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, isAdmin, ok := adminActorFromDB(r.Context())
+	if !ok || !isAdmin {
+		respondWithError(w, http.StatusForbidden, "Admin only")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"recomputed": recomputeSLAAfterCalendarChange(actorID),
+	})
+}
+
+func recomputeSLAAfterCalendarChange(actorID int) int {
+	actor := actorID
+	n, err := repositories.RecomputeOpenSLADueDates(&actor)
+	if err != nil {
+		log.Printf("sla recompute: %v", err)
+	}
+	return n
+}
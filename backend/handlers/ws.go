@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"backend/middleware"
@@ -9,11 +10,15 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// wsMaxMessageSize — входящие сообщения клиента: только подписки, большие кадры не нужны.
+const wsMaxMessageSize = 16 << 10
+
 var wsUpgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
 
-func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
+// realtimeClaims — токен из ?token= (браузерные WebSocket и EventSource не умеют заголовки) или Authorization.
+func realtimeClaims(r *http.Request) (*middleware.Claims, error) {
 	token := strings.TrimSpace(r.URL.Query().Get("token"))
 	if token == "" {
 		auth := r.Header.Get("Authorization")
@@ -21,7 +26,11 @@ func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 			token = strings.TrimPrefix(auth, "Bearer ")
 		}
 	}
-	claims, err := middleware.ParseToken(token)
+	return middleware.ParseToken(token)
+}
+
+func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	claims, err := realtimeClaims(r)
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
@@ -30,19 +39,21 @@ func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
-	client := &realtime.Client{
-		UserID:      claims.UserID,
-		IsModerator: claims.IsModerator || claims.IsAdmin,
-		Conn:        conn,
-		Send:        make(chan []byte, 64),
+	client := realtime.NewClient(claims.UserID, claims.IsModerator || claims.IsAdmin, conn)
+	if client.IsModerator {
+		client.Name = realtimeUserName(claims.UserID)
 	}
+	client.ResumeFrom, _ = strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
 	realtime.RegisterClient(client)
+	conn.SetReadLimit(wsMaxMessageSize)
 	go func() {
 		defer realtime.UnregisterClient(client)
 		for {
-			if _, _, err := conn.ReadMessage(); err != nil {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
 				break
 			}
+			client.HandleMessage(msg)
 		}
 	}()
 }
@@ -9,14 +9,20 @@ import (
 	"backend/realtime"
 	"backend/repositories"
 	"backend/routes"
+	"backend/services"
 
 	"github.com/gorilla/mux"
 )
 
 func main() {
 	database.ConnectDB()
-	realtime.Start()
+	realtime.Start(realtime.BrokerFromEnv(database.DB, database.ConnString()))
 	repositories.SeedClassificationsIfEmpty()
+	if _, err := repositories.SnapshotTaxonomy(nil, "startup"); err != nil {
+		log.Printf("taxonomy snapshot: %v", err)
+	}
+	services.StartSLAEscalationScheduler()
+	services.StartReclassificationWorker()
 	defer database.DB.Close()
 
 	if err := os.MkdirAll("uploads/avatars", 0755); err != nil {
@@ -15,11 +15,16 @@ type Marker struct {
 	GroupKey     string    `json:"group_key,omitempty"`
 	IssueKey     string    `json:"issue_key,omitempty"`
 	AIConfidence *float64  `json:"ai_confidence,omitempty"`
+	AIDomainKey  string    `json:"ai_domain_key,omitempty"`
 	Status            string     `json:"status"`
 	ModeratorNote     string     `json:"moderator_note,omitempty"`
 	ResponseDueAt     *time.Time `json:"response_due_at,omitempty"`
 	ResolutionDueAt   *time.Time `json:"resolution_due_at,omitempty"`
 	ResolvedAt        *time.Time `json:"resolved_at,omitempty"`
+	SLAPausedAt       *time.Time `json:"sla_paused_at,omitempty"`
+	SLAPausedSeconds  int64      `json:"sla_paused_seconds,omitempty"`
+	DepartmentID      *int       `json:"department_id,omitempty"`
+	MergedInto        *int       `json:"merged_into,omitempty"`
 	IsOverdue         bool       `json:"is_overdue,omitempty"`
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
@@ -43,4 +48,19 @@ type CreateMarkerRequest struct {
 	IssueKey       string   `json:"issue_key,omitempty"`
 	AIConfidence   *float64 `json:"ai_confidence,omitempty"`
 	ForceCreate    bool     `json:"force_create,omitempty"`
+	// заполняются сервером по ответу классификатора, из JSON клиента не читаются
+	AIDomainKey string `json:"-"`
+	AIGroupKey  string `json:"-"`
+	AIIssueKey  string `json:"-"`
+	AISource    string `json:"-"`
+}
+
+// MarkerCluster — ячейка сетки с агрегатом меток для мелких масштабов карты.
+type MarkerCluster struct {
+	Count        int     `json:"count"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	DomainKey    string  `json:"domain_key,omitempty"`
+	OverdueCount int     `json:"overdue_count"`
+	MarkerID     *int    `json:"marker_id,omitempty"`
 }
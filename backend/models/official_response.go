@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Авторы записей переписки по метке.
+const (
+	OfficialAuthorDepartment = "department"
+	OfficialAuthorCitizen    = "citizen"
+)
+
+// OfficialResponse — запись переписки: ответ ведомства или ответ заявителя (author_kind = citizen).
+type OfficialResponse struct {
+	ID             int        `json:"id"`
+	MarkerID       int        `json:"marker_id"`
+	ParentID       *int       `json:"parent_id,omitempty"`
+	AuthorKind     string     `json:"author_kind"`
+	DepartmentID   *int       `json:"department_id,omitempty"`
+	DepartmentName string     `json:"department_name,omitempty"`
+	DepartmentIcon string     `json:"department_icon,omitempty"`
+	RespondedBy    *int       `json:"responded_by,omitempty"`
+	AuthorName     string     `json:"author_name,omitempty"`
+	ResponseText   string     `json:"response_text"`
+	ResponseType   string     `json:"response_type"`
+	PlannedDate    *time.Time `json:"planned_date,omitempty"`
+	ActualDate     *time.Time `json:"actual_date,omitempty"`
+	Attachments    []string   `json:"attachments"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type CreateOfficialResponseRequest struct {
+	DepartmentID int      `json:"department_id"`
+	ParentID     *int     `json:"parent_id,omitempty"`
+	ResponseText string   `json:"response_text"`
+	ResponseType string   `json:"response_type"`
+	PlannedDate  string   `json:"planned_date"`
+	ActualDate   string   `json:"actual_date"`
+	Attachments  []string `json:"attachments,omitempty"`
+}
@@ -20,3 +20,11 @@ type NearbyMarker struct {
 	DistanceM    float64 `json:"distance_m"`
 	SupportCount int     `json:"support_count"`
 }
+
+// DuplicateCandidate — соседнее обращение с оценкой похожести на новое.
+type DuplicateCandidate struct {
+	NearbyMarker
+	Score          float64 `json:"score"`
+	TextSimilarity float64 `json:"text_similarity"`
+	SameDomain     bool    `json:"same_domain"`
+}
@@ -6,6 +6,22 @@ type TaxonomyDomain struct {
 	MarkerIcon         string   `json:"marker_icon,omitempty"`
 	ResolutionDays     int      `json:"resolution_days,omitempty"`
 	TrainingPhrasesRu  []string `json:"training_phrases_ru,omitempty"`
+	Groups             []TaxonomyGroup `json:"groups,omitempty"`
+}
+
+// TaxonomyGroup — группа внутри направления (markers.group_key).
+type TaxonomyGroup struct {
+	Key               string          `json:"key"`
+	LabelRu           string          `json:"label_ru"`
+	TrainingPhrasesRu []string        `json:"training_phrases_ru,omitempty"`
+	Issues            []TaxonomyIssue `json:"issues,omitempty"`
+}
+
+// TaxonomyIssue — конкретная проблема внутри группы (markers.issue_key).
+type TaxonomyIssue struct {
+	Key               string   `json:"key"`
+	LabelRu           string   `json:"label_ru"`
+	TrainingPhrasesRu []string `json:"training_phrases_ru,omitempty"`
 }
 
 type Taxonomy struct {
@@ -28,6 +44,9 @@ type UpdateClassificationRequest struct {
 	ResolutionDays    *int     `json:"resolution_days,omitempty"`
 	TrainingPhrasesRu []string `json:"training_phrases_ru,omitempty"`
 	SortOrder         *int     `json:"sort_order,omitempty"`
+	// 0 — вернуть значение по умолчанию
+	DuplicateThreshold *float64 `json:"duplicate_threshold,omitempty"`
+	DuplicateRadiusM   *int     `json:"duplicate_radius_m,omitempty"`
 }
 
 type AdminClassificationRow struct {
@@ -42,6 +61,23 @@ type AdminClassificationRow struct {
 	ResolvedPct       float64  `json:"resolved_pct"`
 	AvgResolutionDays   *float64 `json:"avg_resolution_days,omitempty"`
 	TrainingPhrasesRu   []string `json:"training_phrases_ru,omitempty"`
+	Groups              []TaxonomyGroup `json:"groups,omitempty"`
+	// nil — порог/радиус поиска дублей по умолчанию
+	DuplicateThreshold  *float64 `json:"duplicate_threshold,omitempty"`
+	DuplicateRadiusM    *int     `json:"duplicate_radius_m,omitempty"`
+}
+
+// CreateTaxonomyNodeRequest — новая группа или проблема.
+type CreateTaxonomyNodeRequest struct {
+	Key               string   `json:"key"`
+	LabelRu           string   `json:"label_ru"`
+	TrainingPhrasesRu []string `json:"training_phrases_ru,omitempty"`
+}
+
+type UpdateTaxonomyNodeRequest struct {
+	LabelRu           *string  `json:"label_ru,omitempty"`
+	TrainingPhrasesRu []string `json:"training_phrases_ru,omitempty"`
+	SortOrder         *int     `json:"sort_order,omitempty"`
 }
 
 type ReorderClassificationsRequest struct {
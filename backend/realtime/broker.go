@@ -0,0 +1,109 @@
+package realtime
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Виды сообщений между экземплярами.
+const (
+	EnvelopeEvent    = "event"
+	EnvelopePresence = "presence"
+	// EnvelopeGap — транспорт потерял сообщения (переподключение LISTEN); от своего узла.
+	EnvelopeGap = "gap"
+)
+
+// Envelope — сообщение между экземплярами backend: готовый JSON события и правило доставки
+// либо состояние модераторов, подключённых к узлу.
+type Envelope struct {
+	Node   string          `json:"node"`
+	Kind   string          `json:"kind"`
+	Seq    int64           `json:"seq,omitempty"`
+	Msg    json.RawMessage `json:"msg,omitempty"`
+	Route  *Route          `json:"route,omitempty"`
+	UserID int             `json:"user_id,omitempty"`
+	// Moderators — id модераторов, подключённых к узлу Node (Kind = presence).
+	Moderators []int `json:"moderators,omitempty"`
+	// Review — модератор открыл/закрыл метку (Kind = review).
+	Review *ReviewUpdate `json:"review,omitempty"`
+	// Ref — сообщение не влезло в NOTIFY и лежит в realtime_outbox.
+	Ref int64 `json:"ref,omitempty"`
+}
+
+// Broker — доставка событий всем экземплярам. Publish сразу отдаёт сообщение своему узлу,
+// остальным — через транспорт брокера; deliver вызывается для сообщений других узлов.
+type Broker interface {
+	Start(deliver func(Envelope)) error
+	Publish(env Envelope)
+	// NextSeq — следующий сквозной номер события (общий для всех узлов брокера); 0 — номер не выдан.
+	NextSeq() int64
+	// LastSeq — последний выданный номер: с него начинается история только что запущенного узла.
+	LastSeq() int64
+	Close() error
+}
+
+// BrokerFromEnv — REALTIME_BROKER: postgres (по умолчанию, несколько экземпляров за балансировщиком)
+// или local (один экземпляр, например если БД за pgbouncer в transaction mode без LISTEN).
+func BrokerFromEnv(db *sql.DB, dsn string) Broker {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("REALTIME_BROKER"))) {
+	case "local", "off", "memory":
+		return NewLocalBroker()
+	}
+	if db == nil || dsn == "" {
+		return NewLocalBroker()
+	}
+	return NewPostgresBroker(db, dsn)
+}
+
+// NodeID — идентификатор этого экземпляра; сообщения со своим NodeID из транспорта игнорируются.
+var NodeID = newNodeID()
+
+func newNodeID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "node"
+	}
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// localBroker — один экземпляр: сообщения доставляются только в свой hub.
+type localBroker struct {
+	deliver func(Envelope)
+	seq     int64
+}
+
+// NewLocalBroker — брокер без межузловой доставки. Номера событий начинаются с текущего времени
+// в микросекундах, чтобы после перезапуска не повторять уже выданные клиентам.
+func NewLocalBroker() Broker {
+	return &localBroker{seq: time.Now().UnixMicro()}
+}
+
+func (b *localBroker) NextSeq() int64 {
+	return atomic.AddInt64(&b.seq, 1)
+}
+
+func (b *localBroker) LastSeq() int64 {
+	return atomic.LoadInt64(&b.seq)
+}
+
+func (b *localBroker) Start(deliver func(Envelope)) error {
+	b.deliver = deliver
+	return nil
+}
+
+func (b *localBroker) Publish(env Envelope) {
+	env.Node = NodeID
+	b.deliver(env)
+}
+
+func (b *localBroker) Close() error {
+	return nil
+}
@@ -14,122 +14,288 @@ const (
 	EventMarkerUpdated  = "marker_updated"
 	EventNotification   = "notification"
 	EventModerationPing = "moderation_presence"
+	EventSubscribed     = "subscribed"
+	EventError          = "error"
+	EventPong           = "pong"
+	EventResumed        = "resumed"
+	EventResyncRequired = "resync_required"
 )
 
 type Event struct {
+	// Seq — сквозной номер события для догрузки после переподключения (since=<seq>);
+	// у служебных ответов и присутствия модераторов его нет.
+	Seq       int64       `json:"seq,omitempty"`
 	Type      string      `json:"type"`
 	Payload   interface{} `json:"payload"`
 	Timestamp int64       `json:"ts"`
+	// Route — признаки для фильтрации по подпискам; nil — событие для всех.
+	Route *Route `json:"-"`
 }
 
+// Client — подписчик hub: WebSocket-соединение (Conn) или поток SSE (Conn == nil,
+// обработчик сам читает Send).
 type Client struct {
 	UserID      int
 	IsModerator bool
 	Conn        *websocket.Conn
 	Send        chan []byte
+	// Name — имя для «проверяет X» в панели модерации.
+	Name string
+	// ResumeFrom — последний полученный клиентом seq (since= при подключении).
+	ResumeFrom int64
+	subs       *subscription
+	// reviewHolder — это соединение в отметках «проверяет» (см. reviewEntry)
+	reviewHolder string
+}
+
+// outbound — сериализованное событие и правило доставки.
+type outbound struct {
+	seq   int64
+	msg   []byte
+	route *Route
+	// userID > 0 — только клиентам этого пользователя, без учёта подписок
+	userID int
 }
 
 var Hub = &hub{
 	clients:    make(map[*Client]bool),
-	moderators: make(map[int]bool),
-	broadcast:  make(chan []byte, 256),
+	moderators: make(map[int]int),
+	remote:     make(map[string]remotePresence),
+	broadcast:  make(chan outbound, 256),
 	register:   make(chan *Client),
 	unregister: make(chan *Client),
+	resume:     make(chan resumeRequest, 16),
+	replay:     newReplayBuffer(replayBufferSize),
+	order:      newSeqOrder(),
+	reviews:    newReviewBoard(),
 }
 
 type hub struct {
-	mu         sync.RWMutex
-	clients    map[*Client]bool
-	moderators map[int]bool
-	broadcast  chan []byte
+	mu      sync.RWMutex
+	clients map[*Client]bool
+	// moderators — число соединений модератора с этим узлом (несколько вкладок)
+	moderators map[int]int
+	// remote — модераторы других узлов по последнему heartbeat
+	remote     map[string]remotePresence
+	broker     Broker
+	broadcast  chan outbound
 	register   chan *Client
 	unregister chan *Client
+	resume     chan resumeRequest
+	replay     *replayBuffer
+	order      *seqOrder
+	reviews    *reviewBoard
 }
 
-func Start() {
+// Start — запуск hub; broker == nil — только этот экземпляр.
+func Start(broker Broker) {
+	if broker == nil {
+		broker = NewLocalBroker()
+	}
+	if err := broker.Start(Hub.receive); err != nil {
+		log.Printf("realtime: broker start failed, events stay on this instance: %v", err)
+		broker = NewLocalBroker()
+		_ = broker.Start(Hub.receive)
+	}
+	Hub.mu.Lock()
+	Hub.broker = broker
+	Hub.mu.Unlock()
+	Hub.replay.reset(broker.LastSeq())
+	Hub.order.reset(broker.LastSeq())
 	go Hub.run()
 }
 
 func (h *hub) run() {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+	heartbeat := time.NewTicker(presenceHeartbeat)
+	defer heartbeat.Stop()
+	reviewTicker := time.NewTicker(5 * time.Second)
+	defer reviewTicker.Stop()
+	reorderTicker := time.NewTicker(reorderWait / 2)
+	defer reorderTicker.Stop()
 	for {
 		select {
 		case c := <-h.register:
 			h.mu.Lock()
 			h.clients[c] = true
+			joined := false
 			if c.IsModerator {
-				h.moderators[c.UserID] = true
+				h.moderators[c.UserID]++
+				joined = h.moderators[c.UserID] == 1
 			}
 			h.mu.Unlock()
-			h.broadcastModerationPresence()
-		case c := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[c]; ok {
-				delete(h.clients, c)
-				close(c.Send)
+			if joined {
+				h.presenceChanged()
 			}
-			if c.IsModerator {
-				delete(h.moderators, c.UserID)
+			if c.ResumeFrom > 0 {
+				h.resumeClient(c, c.ResumeFrom)
 			}
+		case req := <-h.resume:
+			h.resumeClient(req.client, req.since)
+		case c := <-h.unregister:
+			h.mu.Lock()
+			left := h.removeClientLocked(c)
 			h.mu.Unlock()
-			h.broadcastModerationPresence()
-		case msg := <-h.broadcast:
-			h.mu.RLock()
-			for c := range h.clients {
-				select {
-				case c.Send <- msg:
-				default:
-					close(c.Send)
-					delete(h.clients, c)
-				}
+			if left {
+				h.presenceChanged()
 			}
-			h.mu.RUnlock()
-		case <-ticker.C:
-			h.mu.RLock()
-			for c := range h.clients {
-				_ = c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-				if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-					close(c.Send)
-					delete(h.clients, c)
-				}
+			for _, markerID := range c.subs.takeReviewing() {
+				releaseReview(markerID, c.UserID, c.reviewHolder)
+			}
+		case out := <-h.broadcast:
+			h.deliver(out)
+		case <-heartbeat.C:
+			h.publishPresence()
+			if h.expireRemotePresence() {
+				h.broadcastModerationPresence()
 			}
-			h.mu.RUnlock()
+		case <-reviewTicker.C:
+			h.expireReviews()
+		case <-reorderTicker.C:
+			h.order.expire(time.Now(), h.release)
 		}
 	}
 }
 
-func (h *hub) broadcastModerationPresence() {
+// publish — через брокер на все узлы, включая этот.
+func (h *hub) publish(env Envelope) {
 	h.mu.RLock()
-	ids := make([]int, 0, len(h.moderators))
-	for id := range h.moderators {
-		ids = append(ids, id)
-	}
-	count := len(h.moderators)
+	b := h.broker
 	h.mu.RUnlock()
-	Broadcast(Event{
-		Type: EventModerationPing,
-		Payload: map[string]interface{}{
-			"online_count": count,
-			"moderator_ids": ids,
-		},
-	})
+	if b == nil {
+		env.Node = NodeID
+		h.receive(env)
+		return
+	}
+	b.Publish(env)
 }
 
-func Broadcast(ev Event) {
-	ev.Timestamp = time.Now().Unix()
-	b, err := json.Marshal(ev)
-	if err != nil {
+// removeClientLocked — под h.mu; true, если ушло последнее соединение модератора.
+func (h *hub) removeClientLocked(c *Client) bool {
+	if _, ok := h.clients[c]; !ok {
+		return false
+	}
+	delete(h.clients, c)
+	close(c.Send)
+	if !c.IsModerator {
+		return false
+	}
+	if h.moderators[c.UserID]--; h.moderators[c.UserID] <= 0 {
+		delete(h.moderators, c.UserID)
+		return true
+	}
+	return false
+}
+
+// receive — сообщение от брокера (своё или другого узла).
+func (h *hub) receive(env Envelope) {
+	switch env.Kind {
+	case EnvelopeEvent:
+		out := outbound{seq: env.Seq, msg: env.Msg, route: env.Route, userID: env.UserID}
+		if out.seq <= 0 {
+			h.release(out)
+			return
+		}
+		h.order.push(out, time.Now(), h.release)
+	case EnvelopeGap:
+		h.markGap(env.Seq)
+	case EnvelopeReview:
+		if env.Review != nil {
+			h.applyReview(*env.Review)
+		}
+	case EnvelopePresence:
+		if env.Node != NodeID && h.setRemotePresence(env.Node, env.Moderators) {
+			h.broadcastModerationPresence()
+		}
+	}
+}
+
+// release — событие прошло упорядочивание по seq: в буфер повтора и клиентам.
+func (h *hub) release(out outbound) {
+	h.replay.add(out)
+	if out.userID > 0 {
+		h.deliver(out)
 		return
 	}
 	select {
-	case Hub.broadcast <- b:
+	case h.broadcast <- out:
 	default:
-		log.Printf("realtime: broadcast channel full, drop %s", ev.Type)
+		log.Printf("realtime: broadcast channel full, drop event")
+	}
+}
+
+// deliver — рассылка подходящим клиентам. Клиент с переполненным буфером отключается:
+// переподключившись с since=<seq>, он догрузит пропущенное из буфера повтора.
+func (h *hub) deliver(out outbound) {
+	var slow []*Client
+	h.mu.RLock()
+	for c := range h.clients {
+		if !out.matches(c) {
+			continue
+		}
+		select {
+		case c.Send <- out.msg:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+	if len(slow) == 0 {
+		return
 	}
+	left := false
+	h.mu.Lock()
+	for _, c := range slow {
+		if h.removeClientLocked(c) {
+			left = true
+		}
+	}
+	h.mu.Unlock()
+	if left {
+		h.presenceChanged()
+	}
+}
+
+func (out outbound) matches(c *Client) bool {
+	if out.userID > 0 {
+		return c.UserID == out.userID
+	}
+	if out.route != nil && out.route.Moderation && !c.IsModerator {
+		return false
+	}
+	if out.route != nil && out.route.Private && !c.IsModerator &&
+		(out.route.OwnerID <= 0 || c.UserID != out.route.OwnerID) {
+		return false
+	}
+	return c.subs.matches(out.route)
+}
+
+// Broadcast — событие всем клиентам всех узлов, чьи подписки совпадают с ev.Route.
+func Broadcast(ev Event) {
+	Hub.publishEvent(ev, 0)
 }
 
+// BroadcastToUser — событие всем соединениям пользователя на любом узле.
 func BroadcastToUser(userID int, ev Event) {
+	Hub.publishEvent(ev, userID)
+}
+
+func (h *hub) publishEvent(ev Event, userID int) {
+	h.mu.RLock()
+	b := h.broker
+	h.mu.RUnlock()
+	if b != nil {
+		ev.Seq = b.NextSeq()
+	}
+	ev.Timestamp = time.Now().Unix()
+	msg, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	h.publish(Envelope{Kind: EnvelopeEvent, Seq: ev.Seq, Msg: msg, Route: ev.Route, UserID: userID})
+}
+
+// reply — ответ одному клиенту (подтверждение подписки, ошибка).
+func (c *Client) reply(ev Event) {
 	ev.Timestamp = time.Now().Unix()
 	b, err := json.Marshal(ev)
 	if err != nil {
@@ -137,40 +303,77 @@ func BroadcastToUser(userID int, ev Event) {
 	}
 	Hub.mu.RLock()
 	defer Hub.mu.RUnlock()
-	for c := range Hub.clients {
-		if c.UserID == userID {
-			select {
-			case c.Send <- b:
-			default:
-			}
+	if Hub.clients[c] {
+		select {
+		case c.Send <- b:
+		default:
 		}
 	}
 }
 
+// NewClient — клиент с пустыми подписками; conn == nil для SSE.
+func NewClient(userID int, isModerator bool, conn *websocket.Conn) *Client {
+	return &Client{
+		UserID:       userID,
+		IsModerator:  isModerator,
+		Conn:         conn,
+		Send:         make(chan []byte, 64),
+		subs:         &subscription{},
+		reviewHolder: newReviewHolder(),
+	}
+}
+
 func RegisterClient(c *Client) {
+	if c.subs == nil {
+		c.subs = &subscription{}
+	}
 	Hub.register <- c
-	go c.writePump()
+	if c.Conn != nil {
+		go c.writePump()
+	}
+}
+
+// ResumeClient — догрузить клиенту события после since (сообщение {"action":"resume","since":N}).
+func ResumeClient(c *Client, since int64) {
+	select {
+	case Hub.resume <- resumeRequest{client: c, since: since}:
+	default:
+		c.reply(Event{Type: EventResyncRequired, Payload: map[string]interface{}{"since": since}})
+	}
 }
 
 func UnregisterClient(c *Client) {
 	Hub.unregister <- c
 }
 
+// writePump — единственный писатель в соединение: события и ping раз в 30 секунд.
 func (c *Client) writePump() {
+	ticker := time.NewTicker(30 * time.Second)
 	defer func() {
+		ticker.Stop()
 		Hub.unregister <- c
 		_ = c.Conn.Close()
 	}()
-	for msg := range c.Send {
-		_ = c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-		if err := c.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-			return
+	for {
+		select {
+		case msg, ok := <-c.Send:
+			if !ok {
+				return
+			}
+			_ = c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := c.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
 
+// ModeratorOnlineCount — модераторы онлайн на всех узлах.
 func ModeratorOnlineCount() int {
-	Hub.mu.RLock()
-	defer Hub.mu.RUnlock()
-	return len(Hub.moderators)
+	return len(Hub.onlineModerators())
 }
@@ -0,0 +1,164 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	pgNotifyChannel = "realtime_events"
+	// лимит payload у NOTIFY — 8000 байт; крупные события идут через realtime_outbox
+	pgMaxNotifyPayload = 7900
+	pgOutboxTTL        = 10 * time.Minute
+)
+
+// pgBroker — fan-out между экземплярами через Postgres LISTEN/NOTIFY.
+type pgBroker struct {
+	db       *sql.DB
+	dsn      string
+	listener *pq.Listener
+	deliver  func(Envelope)
+	out      chan Envelope
+	done     chan struct{}
+}
+
+// NewPostgresBroker — брокер поверх уже подключённой БД; dsn нужен отдельному LISTEN-соединению.
+func NewPostgresBroker(db *sql.DB, dsn string) Broker {
+	return &pgBroker{
+		db:   db,
+		dsn:  dsn,
+		out:  make(chan Envelope, 1024),
+		done: make(chan struct{}),
+	}
+}
+
+func (b *pgBroker) Start(deliver func(Envelope)) error {
+	b.deliver = deliver
+	b.listener = pq.NewListener(b.dsn, 2*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			log.Printf("realtime: LISTEN connection lost: %v", err)
+		case pq.ListenerEventReconnected:
+			log.Printf("realtime: LISTEN connection restored")
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("realtime: LISTEN reconnect failed: %v", err)
+		}
+	})
+	if err := b.listener.Listen(pgNotifyChannel); err != nil {
+		_ = b.listener.Close()
+		return err
+	}
+	go b.listen()
+	go b.publishLoop()
+	return nil
+}
+
+// Publish — своему узлу сразу, остальным асинхронно (порядок сохраняется одной очередью).
+func (b *pgBroker) Publish(env Envelope) {
+	env.Node = NodeID
+	b.deliver(env)
+	select {
+	case b.out <- env:
+	default:
+		log.Printf("realtime: NOTIFY queue full, drop %s for other nodes", env.Kind)
+	}
+}
+
+func (b *pgBroker) NextSeq() int64 {
+	var seq int64
+	if err := b.db.QueryRow(`SELECT nextval('realtime_event_seq')`).Scan(&seq); err != nil {
+		log.Printf("realtime: nextval: %v", err)
+		return 0
+	}
+	return seq
+}
+
+func (b *pgBroker) LastSeq() int64 {
+	var seq int64
+	_ = b.db.QueryRow(`
+		SELECT CASE WHEN is_called THEN last_value ELSE last_value - 1 END FROM realtime_event_seq`,
+	).Scan(&seq)
+	return seq
+}
+
+func (b *pgBroker) Close() error {
+	close(b.done)
+	return b.listener.Close()
+}
+
+func (b *pgBroker) publishLoop() {
+	cleanup := time.NewTicker(time.Minute)
+	defer cleanup.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case env := <-b.out:
+			if err := b.notify(env); err != nil {
+				log.Printf("realtime: NOTIFY: %v", err)
+			}
+		case <-cleanup.C:
+			_, _ = b.db.Exec(`DELETE FROM realtime_outbox WHERE created_at < $1`, time.Now().Add(-pgOutboxTTL))
+		}
+	}
+}
+
+func (b *pgBroker) notify(env Envelope) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	if len(payload) > pgMaxNotifyPayload {
+		var id int64
+		if err := b.db.QueryRow(
+			`INSERT INTO realtime_outbox (payload) VALUES ($1) RETURNING id`, payload,
+		).Scan(&id); err != nil {
+			return err
+		}
+		payload, _ = json.Marshal(Envelope{Node: env.Node, Kind: env.Kind, Ref: id})
+	}
+	_, err = b.db.Exec(`SELECT pg_notify($1, $2)`, pgNotifyChannel, string(payload))
+	return err
+}
+
+func (b *pgBroker) listen() {
+	// ping держит соединение LISTEN живым и быстрее замечает обрыв
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			// nil — соединение переподключено, часть сообщений могла потеряться
+			if n == nil {
+				b.deliver(Envelope{Node: NodeID, Kind: EnvelopeGap, Seq: b.LastSeq()})
+				continue
+			}
+			var env Envelope
+			if err := json.Unmarshal([]byte(n.Extra), &env); err != nil || env.Node == NodeID {
+				continue
+			}
+			if env.Ref > 0 {
+				var raw []byte
+				if err := b.db.QueryRow(`SELECT payload FROM realtime_outbox WHERE id = $1`, env.Ref).Scan(&raw); err != nil {
+					log.Printf("realtime: outbox %d: %v", env.Ref, err)
+					continue
+				}
+				if err := json.Unmarshal(raw, &env); err != nil {
+					continue
+				}
+			}
+			b.deliver(env)
+		case <-ping.C:
+			go func() { _ = b.listener.Ping() }()
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+const (
+	// presenceHeartbeat — как часто узел рассылает своих модераторов
+	presenceHeartbeat = 20 * time.Second
+	// presenceTTL — узел без heartbeat считается остановленным
+	presenceTTL = 3 * presenceHeartbeat
+)
+
+// remotePresence — модераторы другого узла.
+type remotePresence struct {
+	moderators []int
+	seenAt     time.Time
+}
+
+func (h *hub) localModeratorIDs() []int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ids := make([]int, 0, len(h.moderators))
+	for id := range h.moderators {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// onlineModerators — объединение модераторов всех узлов.
+func (h *hub) onlineModerators() []int {
+	h.mu.RLock()
+	seen := make(map[int]bool, len(h.moderators))
+	for id := range h.moderators {
+		seen[id] = true
+	}
+	for _, p := range h.remote {
+		for _, id := range p.moderators {
+			seen[id] = true
+		}
+	}
+	h.mu.RUnlock()
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// presenceChanged — состав модераторов этого узла изменился: сообщить другим узлам и своим клиентам.
+func (h *hub) presenceChanged() {
+	h.publishPresence()
+	h.broadcastModerationPresence()
+}
+
+func (h *hub) publishPresence() {
+	h.publish(Envelope{Kind: EnvelopePresence, Moderators: h.localModeratorIDs()})
+}
+
+// setRemotePresence — heartbeat другого узла; true, если состав модераторов узла изменился.
+func (h *hub) setRemotePresence(node string, ids []int) bool {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prev, known := h.remote[node]
+	h.remote[node] = remotePresence{moderators: sorted, seenAt: time.Now()}
+	if !known {
+		return len(sorted) > 0
+	}
+	return !equalInts(prev.moderators, sorted)
+}
+
+// expireRemotePresence — забыть узлы без heartbeat; true, если кто-то из модераторов пропал.
+func (h *hub) expireRemotePresence() bool {
+	cutoff := time.Now().Add(-presenceTTL)
+	changed := false
+	h.mu.Lock()
+	for node, p := range h.remote {
+		if p.seenAt.Before(cutoff) {
+			delete(h.remote, node)
+			changed = changed || len(p.moderators) > 0
+		}
+	}
+	h.mu.Unlock()
+	return changed
+}
+
+// broadcastModerationPresence — общий по всем узлам список модераторов, только клиентам этого узла:
+// каждый узел сам собирает картину из heartbeat'ов.
+func (h *hub) broadcastModerationPresence() {
+	ids := h.onlineModerators()
+	ev := Event{
+		Type: EventModerationPing,
+		Payload: map[string]interface{}{
+			"online_count":  len(ids),
+			"moderator_ids": ids,
+		},
+		Timestamp: time.Now().Unix(),
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	select {
+	case h.broadcast <- outbound{msg: b, route: &Route{Moderation: true}}:
+	default:
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
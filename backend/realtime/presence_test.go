@@ -0,0 +1,51 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestHub() *hub {
+	return &hub{
+		clients:    make(map[*Client]bool),
+		moderators: make(map[int]int),
+		remote:     make(map[string]remotePresence),
+		broadcast:  make(chan outbound, 16),
+		replay:     newReplayBuffer(4),
+		order:      newSeqOrder(),
+	}
+}
+
+func TestPresenceAggregatesNodes(t *testing.T) {
+	h := newTestHub()
+	h.moderators[1] = 2
+	if !h.setRemotePresence("node-b", []int{3, 1}) {
+		t.Fatal("new node with moderators should change presence")
+	}
+	if h.setRemotePresence("node-b", []int{1, 3}) {
+		t.Error("same moderators in another order is not a change")
+	}
+	if got := h.onlineModerators(); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("expected [1 3], got %v", got)
+	}
+
+	h.remote["node-b"] = remotePresence{moderators: []int{3}, seenAt: time.Now().Add(-2 * presenceTTL)}
+	if !h.expireRemotePresence() {
+		t.Fatal("stale node should expire")
+	}
+	if got := h.onlineModerators(); len(got) != 1 || got[0] != 1 {
+		t.Errorf("expected [1] after expiry, got %v", got)
+	}
+}
+
+func TestReceiveIgnoresOwnPresence(t *testing.T) {
+	h := newTestHub()
+	h.receive(Envelope{Node: NodeID, Kind: EnvelopePresence, Moderators: []int{5}})
+	if len(h.remote) != 0 {
+		t.Error("own presence heartbeat must not be stored as remote")
+	}
+	h.receive(Envelope{Node: "other", Kind: EnvelopeEvent, Msg: []byte(`{}`)})
+	if len(h.broadcast) != 1 {
+		t.Error("event from another node should be queued for local clients")
+	}
+}
@@ -0,0 +1,241 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// replayBufferSize — сколько последних событий узел хранит для переподключившихся клиентов.
+const replayBufferSize = 2048
+
+// replayBuffer — кольцевой буфер событий с seq. horizon — наибольший seq, которого в буфере
+// уже (или ещё) нет: клиенту с since < horizon часть событий не восстановить.
+type replayBuffer struct {
+	mu      sync.Mutex
+	entries []outbound
+	next    int
+	full    bool
+	horizon int64
+	last    int64
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{entries: make([]outbound, size)}
+}
+
+// reset — пустой буфер, история начинается после lastSeq.
+func (b *replayBuffer) reset(lastSeq int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.entries {
+		b.entries[i] = outbound{}
+	}
+	b.next, b.full = 0, false
+	b.horizon, b.last = lastSeq, lastSeq
+}
+
+func (b *replayBuffer) add(out outbound) {
+	if out.seq <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.full {
+		if evicted := b.entries[b.next].seq; evicted > b.horizon {
+			b.horizon = evicted
+		}
+	}
+	b.entries[b.next] = out
+	b.next++
+	if b.next == len(b.entries) {
+		b.next, b.full = 0, true
+	}
+	if out.seq > b.last {
+		b.last = out.seq
+	}
+}
+
+// since — события с seq > since по возрастанию seq (в буфер они попадают через seqOrder);
+// ok = false, если часть уже вытеснена.
+func (b *replayBuffer) since(since int64) (out []outbound, last int64, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if since < b.horizon {
+		return nil, b.last, false
+	}
+	n := b.next
+	start := 0
+	if b.full {
+		n = len(b.entries)
+		start = b.next
+	}
+	for i := 0; i < n; i++ {
+		e := b.entries[(start+i)%len(b.entries)]
+		if e.seq > since {
+			out = append(out, e)
+		}
+	}
+	return out, b.last, true
+}
+
+// reorderWait — сколько события ждут пропущенный перед ними seq: номер выдан, но публикация
+// не дошла или не удалась. Дольше разрыв не держим — события за ним отдаются как есть.
+const reorderWait = 2 * time.Second
+
+// seqOrder — выпуск событий клиентам и в буфер повтора строго по возрастанию seq. Номер выдаётся
+// до публикации, и меньший seq может прийти позже большего; клиент, переподключившись с
+// since = наибольшему полученному, иначе потерял бы такие события.
+type seqOrder struct {
+	mu       sync.Mutex
+	released int64
+	pending  map[int64]pendingEvent
+}
+
+type pendingEvent struct {
+	out     outbound
+	arrived time.Time
+}
+
+func newSeqOrder() *seqOrder {
+	return &seqOrder{pending: make(map[int64]pendingEvent)}
+}
+
+// reset — следующим ожидается lastSeq+1.
+func (o *seqOrder) reset(lastSeq int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.released = lastSeq
+	o.pending = make(map[int64]pendingEvent)
+}
+
+// push — событие от брокера. release вызывается под блокировкой для каждого события, которое
+// можно отдать, — иначе параллельные публикации снова перемешали бы порядок.
+func (o *seqOrder) push(out outbound, now time.Time, release func(outbound)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if out.seq <= o.released {
+		// опоздало после пропуска разрыва или номер выдан до старта узла
+		release(out)
+		return
+	}
+	o.pending[out.seq] = pendingEvent{out: out, arrived: now}
+	o.drain(release)
+}
+
+// expire — пропустить разрыв, если какое-то событие за ним ждёт дольше reorderWait.
+func (o *seqOrder) expire(now time.Time, release func(outbound)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for len(o.pending) > 0 {
+		var first int64
+		oldest := now
+		for seq, p := range o.pending {
+			if first == 0 || seq < first {
+				first = seq
+			}
+			if p.arrived.Before(oldest) {
+				oldest = p.arrived
+			}
+		}
+		if now.Sub(oldest) < reorderWait {
+			return
+		}
+		o.released = first - 1
+		o.drain(release)
+	}
+}
+
+// skipTo — события до lastSeq уже не придут (обрыв LISTEN): отдать ожидающие и идти дальше.
+func (o *seqOrder) skipTo(lastSeq int64, release func(outbound)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for o.released < lastSeq {
+		o.released++
+		if p, ok := o.pending[o.released]; ok {
+			delete(o.pending, o.released)
+			release(p.out)
+		}
+	}
+	o.drain(release)
+}
+
+// drain — под o.mu: отдать всё, что идёт подряд после released.
+func (o *seqOrder) drain(release func(outbound)) {
+	for {
+		p, ok := o.pending[o.released+1]
+		if !ok {
+			return
+		}
+		delete(o.pending, o.released+1)
+		o.released++
+		release(p.out)
+	}
+}
+
+// resumeRequest — клиент просит догрузить события после since (action "resume").
+type resumeRequest struct {
+	client *Client
+	since  int64
+}
+
+// resumeClient — вызывается из цикла hub, чтобы повтор не перемешался с рассылкой.
+// Пропущенное отправляется в порядке поступления; при разрыве больше буфера — resync_required,
+// клиент должен заново загрузить данные по REST.
+func (h *hub) resumeClient(c *Client, since int64) {
+	events, last, ok := h.replay.since(since)
+	if !ok {
+		c.reply(Event{Type: EventResyncRequired, Payload: map[string]interface{}{
+			"since":    since,
+			"last_seq": last,
+		}})
+		return
+	}
+	h.mu.RLock()
+	if !h.clients[c] {
+		h.mu.RUnlock()
+		return
+	}
+	sent := 0
+	for _, e := range events {
+		if !e.matches(c) {
+			continue
+		}
+		select {
+		case c.Send <- e.msg:
+			sent++
+		default:
+			// буфер клиента меньше пропущенного — дальше только полная перезагрузка
+			h.mu.RUnlock()
+			c.reply(Event{Type: EventResyncRequired, Payload: map[string]interface{}{
+				"since":    since,
+				"last_seq": last,
+			}})
+			return
+		}
+	}
+	h.mu.RUnlock()
+	c.reply(Event{Type: EventResumed, Payload: map[string]interface{}{
+		"since":    since,
+		"replayed": sent,
+		"last_seq": last,
+	}})
+}
+
+// markGap — узел пропустил события (обрыв LISTEN): повтор раньше lastSeq невозможен,
+// а подключённым клиентам нужно перезагрузить данные.
+func (h *hub) markGap(lastSeq int64) {
+	h.order.skipTo(lastSeq, h.release)
+	h.replay.mu.Lock()
+	if lastSeq > h.replay.horizon {
+		h.replay.horizon = lastSeq
+	}
+	if lastSeq > h.replay.last {
+		h.replay.last = lastSeq
+	}
+	h.replay.mu.Unlock()
+	ev := Event{Type: EventResyncRequired, Payload: map[string]interface{}{"last_seq": lastSeq}, Timestamp: time.Now().Unix()}
+	if msg, err := json.Marshal(ev); err == nil {
+		h.deliver(outbound{msg: msg})
+	}
+}
@@ -0,0 +1,76 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplayBufferSince(t *testing.T) {
+	b := newReplayBuffer(3)
+	b.reset(100)
+	if _, _, ok := b.since(99); ok {
+		t.Fatal("events before node start cannot be replayed")
+	}
+	for seq := int64(101); seq <= 103; seq++ {
+		b.add(outbound{seq: seq, msg: []byte("x")})
+	}
+	got, last, ok := b.since(101)
+	if !ok || len(got) != 2 || got[0].seq != 102 || last != 103 {
+		t.Fatalf("since(101) = %v, %d, %v", got, last, ok)
+	}
+
+	b.add(outbound{seq: 104})
+	if _, _, ok := b.since(100); ok {
+		t.Error("evicted event 101 must force resync")
+	}
+	got, _, ok = b.since(101)
+	if !ok || len(got) != 3 || got[0].seq != 102 || got[2].seq != 104 {
+		t.Errorf("since(101) after wrap = %v, %v", got, ok)
+	}
+	b.add(outbound{msg: []byte("no seq")})
+	if got, _, _ := b.since(104); len(got) != 0 {
+		t.Error("events without seq are not buffered")
+	}
+}
+
+func TestSeqOrderReleasesInSeqOrder(t *testing.T) {
+	o := newSeqOrder()
+	o.reset(10)
+	var got []int64
+	release := func(out outbound) { got = append(got, out.seq) }
+	now := time.Now()
+
+	// 12 опубликован раньше 11: клиент не должен увидеть 12, пока не пришло 11
+	o.push(outbound{seq: 12}, now, release)
+	if len(got) != 0 {
+		t.Fatalf("released %v before seq 11 arrived", got)
+	}
+	o.push(outbound{seq: 11}, now, release)
+	o.push(outbound{seq: 13}, now, release)
+	if len(got) != 3 || got[0] != 11 || got[1] != 12 || got[2] != 13 {
+		t.Fatalf("released %v, want [11 12 13]", got)
+	}
+
+	// 14 так и не пришло — после reorderWait разрыв пропускается
+	got = nil
+	o.push(outbound{seq: 15}, now, release)
+	o.expire(now.Add(reorderWait/2), release)
+	if len(got) != 0 {
+		t.Fatalf("gap skipped too early: %v", got)
+	}
+	o.expire(now.Add(reorderWait), release)
+	if len(got) != 1 || got[0] != 15 {
+		t.Fatalf("released %v after expiry, want [15]", got)
+	}
+	o.push(outbound{seq: 14}, now, release)
+	if len(got) != 2 || got[1] != 14 {
+		t.Errorf("late seq 14 must still be delivered, got %v", got)
+	}
+
+	got = nil
+	o.push(outbound{seq: 18}, now, release)
+	o.skipTo(17, release)
+	if len(got) != 1 || got[0] != 18 {
+		t.Errorf("skipTo(17) released %v, want [18]", got)
+	}
+}
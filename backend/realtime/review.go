@@ -0,0 +1,247 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	EventMarkerReview = "marker_review"
+	// EnvelopeReview — модератор открыл или закрыл метку на каком-то узле.
+	EnvelopeReview = "review"
+
+	// ReviewLockTTL — сколько живёт отметка «проверяет» без продления; клиент продлевает её каждые ~30 с.
+	ReviewLockTTL = 90 * time.Second
+
+	// restReviewHolder — отметки через REST: у HTTP-клиента нет соединения, он держит одну отметку на метку.
+	restReviewHolder = "http"
+)
+
+// Reviewer — модератор, открывший метку в панели модерации.
+type Reviewer struct {
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name,omitempty"`
+	Since     time.Time `json:"since"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ReviewUpdate — изменение присутствия на метке (между узлами).
+// Holder — соединение (вкладка), которое открыло или закрыло метку.
+type ReviewUpdate struct {
+	MarkerID int      `json:"marker_id"`
+	Reviewer Reviewer `json:"reviewer"`
+	Holder   string   `json:"holder,omitempty"`
+	Released bool     `json:"released,omitempty"`
+}
+
+// reviewEntry — отметка модератора на метке. Модератор может открыть метку в нескольких вкладках
+// (в том числе на разных узлах): отметка снимается, когда её отпустило последнее соединение.
+type reviewEntry struct {
+	Reviewer
+	holders map[string]time.Time
+}
+
+// reviewBoard — кто какие метки сейчас смотрит. Блокировка мягкая: держит тот, кто открыл метку
+// раньше всех; у всех узлов одинаковый ответ, так как since приходит вместе с обновлением.
+type reviewBoard struct {
+	mu      sync.RWMutex
+	markers map[int]map[int]*reviewEntry
+}
+
+func newReviewBoard() *reviewBoard {
+	return &reviewBoard{markers: make(map[int]map[int]*reviewEntry)}
+}
+
+// apply — true, если состав проверяющих изменился (продление срока и лишняя вкладка — не изменение).
+func (b *reviewBoard) apply(u ReviewUpdate) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cur := b.markers[u.MarkerID]
+	e, had := cur[u.Reviewer.UserID]
+	if u.Released {
+		if !had {
+			return false
+		}
+		delete(e.holders, u.Holder)
+		if len(e.holders) > 0 {
+			e.refreshExpiry()
+			return false
+		}
+		delete(cur, u.Reviewer.UserID)
+		if len(cur) == 0 {
+			delete(b.markers, u.MarkerID)
+		}
+		return true
+	}
+	if cur == nil {
+		cur = make(map[int]*reviewEntry)
+		b.markers[u.MarkerID] = cur
+	}
+	if !had {
+		e = &reviewEntry{holders: make(map[string]time.Time)}
+		cur[u.Reviewer.UserID] = e
+	}
+	since := u.Reviewer.Since
+	if had && e.Since.Before(since) {
+		since = e.Since
+	}
+	e.Reviewer = u.Reviewer
+	e.Since = since
+	e.holders[u.Holder] = u.Reviewer.ExpiresAt
+	e.refreshExpiry()
+	return !had
+}
+
+// refreshExpiry — отметка живёт, пока жив срок хотя бы одного соединения.
+func (e *reviewEntry) refreshExpiry() {
+	var last time.Time
+	for _, exp := range e.holders {
+		if exp.After(last) {
+			last = exp
+		}
+	}
+	e.ExpiresAt = last
+}
+
+func (b *reviewBoard) get(markerID, userID int) (Reviewer, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	e, ok := b.markers[markerID][userID]
+	if !ok {
+		return Reviewer{}, false
+	}
+	return e.Reviewer, true
+}
+
+// reviewers — действующие проверяющие, первым — держатель блокировки.
+func (b *reviewBoard) reviewers(markerID int, now time.Time) []Reviewer {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]Reviewer, 0, len(b.markers[markerID]))
+	for _, e := range b.markers[markerID] {
+		if e.ExpiresAt.After(now) {
+			out = append(out, e.Reviewer)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Since.Equal(out[j].Since) {
+			return out[i].Since.Before(out[j].Since)
+		}
+		return out[i].UserID < out[j].UserID
+	})
+	return out
+}
+
+// expire — снять просроченные отметки; возвращает метки, где состав изменился.
+// Соединение, которое перестало продлевать отметку (например, узел упал), снимается по своему сроку.
+func (b *reviewBoard) expire(now time.Time) []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var changed []int
+	for markerID, cur := range b.markers {
+		n := len(cur)
+		for uid, e := range cur {
+			for holder, exp := range e.holders {
+				if !exp.After(now) {
+					delete(e.holders, holder)
+				}
+			}
+			if len(e.holders) == 0 {
+				delete(cur, uid)
+				continue
+			}
+			e.refreshExpiry()
+		}
+		if len(cur) != n {
+			changed = append(changed, markerID)
+		}
+		if len(cur) == 0 {
+			delete(b.markers, markerID)
+		}
+	}
+	return changed
+}
+
+// StartMarkerReview — модератор открыл метку через REST (или продлевает отметку). Возвращает текущих проверяющих.
+func StartMarkerReview(markerID, userID int, name string) []Reviewer {
+	return startReview(markerID, userID, name, restReviewHolder)
+}
+
+// ReleaseMarkerReview — модератор закрыл метку через REST; вкладки с WebSocket держат свои отметки.
+func ReleaseMarkerReview(markerID, userID int) {
+	releaseReview(markerID, userID, restReviewHolder)
+}
+
+func startReview(markerID, userID int, name, holder string) []Reviewer {
+	now := time.Now()
+	r := Reviewer{UserID: userID, Name: name, Since: now, ExpiresAt: now.Add(ReviewLockTTL)}
+	if prev, ok := Hub.reviews.get(markerID, userID); ok && prev.ExpiresAt.After(now) {
+		r.Since = prev.Since
+	}
+	Hub.publish(Envelope{Kind: EnvelopeReview, Review: &ReviewUpdate{MarkerID: markerID, Reviewer: r, Holder: holder}})
+	return MarkerReviewers(markerID)
+}
+
+func releaseReview(markerID, userID int, holder string) {
+	Hub.publish(Envelope{Kind: EnvelopeReview, Review: &ReviewUpdate{
+		MarkerID: markerID, Reviewer: Reviewer{UserID: userID}, Holder: holder, Released: true,
+	}})
+}
+
+var clientSeq int64
+
+// newReviewHolder — имя соединения в отметках, уникальное между узлами.
+func newReviewHolder() string {
+	return fmt.Sprintf("%s/%d", NodeID, atomic.AddInt64(&clientSeq, 1))
+}
+
+// MarkerReviewers — кто сейчас проверяет метку (по всем узлам).
+func MarkerReviewers(markerID int) []Reviewer {
+	return Hub.reviews.reviewers(markerID, time.Now())
+}
+
+// MarkerReviewLock — держатель мягкой блокировки метки, если он есть.
+func MarkerReviewLock(markerID int) (Reviewer, bool) {
+	list := MarkerReviewers(markerID)
+	if len(list) == 0 {
+		return Reviewer{}, false
+	}
+	return list[0], true
+}
+
+func (h *hub) applyReview(u ReviewUpdate) {
+	if h.reviews.apply(u) {
+		h.broadcastMarkerReview(u.MarkerID)
+	}
+}
+
+func (h *hub) expireReviews() {
+	for _, markerID := range h.reviews.expire(time.Now()) {
+		h.broadcastMarkerReview(markerID)
+	}
+}
+
+// broadcastMarkerReview — состав проверяющих метки, только модераторам этого узла
+// (каждый узел сам собирает картину из обновлений, как и присутствие модераторов).
+func (h *hub) broadcastMarkerReview(markerID int) {
+	list := h.reviews.reviewers(markerID, time.Now())
+	payload := map[string]interface{}{
+		"marker_id": markerID,
+		"reviewers": list,
+	}
+	if len(list) > 0 {
+		payload["lock_holder"] = list[0].UserID
+	}
+	b, err := json.Marshal(Event{Type: EventMarkerReview, Payload: payload, Timestamp: time.Now().Unix()})
+	if err != nil {
+		return
+	}
+	select {
+	case h.broadcast <- outbound{msg: b, route: &Route{MarkerID: markerID, Moderation: true}}:
+	default:
+	}
+}
@@ -0,0 +1,67 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReviewBoardLockHolder(t *testing.T) {
+	b := newReviewBoard()
+	now := time.Now()
+	first := Reviewer{UserID: 7, Name: "Анна", Since: now.Add(-time.Minute), ExpiresAt: now.Add(time.Minute)}
+	second := Reviewer{UserID: 3, Name: "Иван", Since: now, ExpiresAt: now.Add(time.Minute)}
+
+	if !b.apply(ReviewUpdate{MarkerID: 1, Reviewer: second}) || !b.apply(ReviewUpdate{MarkerID: 1, Reviewer: first}) {
+		t.Fatal("new reviewers must change the board")
+	}
+	list := b.reviewers(1, now)
+	if len(list) != 2 || list[0].UserID != 7 {
+		t.Fatalf("earliest reviewer should hold the lock, got %+v", list)
+	}
+	if b.apply(ReviewUpdate{MarkerID: 1, Reviewer: Reviewer{UserID: 3, Since: second.Since, ExpiresAt: now.Add(2 * time.Minute)}}) {
+		t.Error("extending a review is not a change")
+	}
+
+	if !b.apply(ReviewUpdate{MarkerID: 1, Reviewer: Reviewer{UserID: 7}, Released: true}) {
+		t.Fatal("release should change the board")
+	}
+	if list := b.reviewers(1, now); len(list) != 1 || list[0].UserID != 3 {
+		t.Errorf("lock should pass to the next reviewer, got %+v", list)
+	}
+
+	if changed := b.expire(now.Add(3 * time.Minute)); len(changed) != 1 || changed[0] != 1 {
+		t.Errorf("expired review should be reported, got %v", changed)
+	}
+	if len(b.reviewers(1, now)) != 0 {
+		t.Error("board should be empty after expiry")
+	}
+}
+
+func TestReviewBoardKeepsLockWhileAnotherTabHoldsIt(t *testing.T) {
+	b := newReviewBoard()
+	now := time.Now()
+	r := Reviewer{UserID: 7, Since: now, ExpiresAt: now.Add(time.Minute)}
+	if !b.apply(ReviewUpdate{MarkerID: 1, Reviewer: r, Holder: "a/1"}) {
+		t.Fatal("first tab should add the reviewer")
+	}
+	later := Reviewer{UserID: 7, Since: now.Add(time.Second), ExpiresAt: now.Add(2 * time.Minute)}
+	if b.apply(ReviewUpdate{MarkerID: 1, Reviewer: later, Holder: "b/1"}) {
+		t.Error("second tab of the same moderator is not a change")
+	}
+
+	if b.apply(ReviewUpdate{MarkerID: 1, Reviewer: Reviewer{UserID: 7}, Holder: "a/1", Released: true}) {
+		t.Fatal("closing one tab must not release the lock")
+	}
+	list := b.reviewers(1, now)
+	if len(list) != 1 || !list[0].Since.Equal(now) {
+		t.Fatalf("lock should stay with its original since, got %+v", list)
+	}
+
+	// вкладка на упавшем узле не продлевает отметку — она снимается по своему сроку
+	if changed := b.expire(now.Add(3 * time.Minute)); len(changed) != 1 {
+		t.Errorf("expired holders should drop the reviewer, got %v", changed)
+	}
+	if len(b.reviewers(1, now)) != 0 {
+		t.Error("board should be empty after the last tab expired")
+	}
+}
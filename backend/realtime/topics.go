@@ -0,0 +1,287 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Темы подписки клиента.
+const (
+	TopicViewport   = "viewport"
+	TopicMarkers    = "markers"
+	TopicDomains    = "domains"
+	TopicModeration = "moderation"
+)
+
+const (
+	maxSubscribedMarkers = 500
+	maxSubscribedDomains = 50
+)
+
+// Route — по каким признакам событие доставляется подписчикам. В JSON клиенту не уходит.
+type Route struct {
+	MarkerID    int     `json:"marker_id,omitempty"`
+	HasLocation bool    `json:"has_location,omitempty"`
+	Lat         float64 `json:"lat,omitempty"`
+	Lng         float64 `json:"lng,omitempty"`
+	DomainKey   string  `json:"domain_key,omitempty"`
+	// Moderation — событие очереди модерации (присутствие модераторов и т.п.).
+	Moderation bool `json:"moderation,omitempty"`
+	// Private — метка не опубликована (на проверке, отклонена, слита): событие получают
+	// только модераторы и автор OwnerID, какие бы темы ни выбрали остальные.
+	Private bool `json:"private,omitempty"`
+	OwnerID int  `json:"owner_id,omitempty"`
+}
+
+// BBox — прямоугольник карты: юго-западный и северо-восточный углы.
+type BBox struct {
+	SWLat float64 `json:"sw_lat"`
+	SWLng float64 `json:"sw_lng"`
+	NELat float64 `json:"ne_lat"`
+	NELng float64 `json:"ne_lng"`
+}
+
+func (b BBox) contains(lat, lng float64) bool {
+	if lat < b.SWLat || lat > b.NELat {
+		return false
+	}
+	if b.SWLng <= b.NELng {
+		return lng >= b.SWLng && lng <= b.NELng
+	}
+	// прямоугольник через антимеридиан
+	return lng >= b.SWLng || lng <= b.NELng
+}
+
+// subscription — темы клиента. Пока клиент ни на что не подписался, он получает все события
+// (старые версии фронтенда подписок не отправляют).
+type subscription struct {
+	mu         sync.RWMutex
+	active     bool
+	viewport   *BBox
+	markers    map[int]bool
+	domains    map[string]bool
+	moderation bool
+	// reviewing — метки, открытые клиентом в панели модерации (снимаются при отключении)
+	reviewing map[int]bool
+}
+
+// takeReviewing — открытые клиентом метки; список очищается.
+func (s *subscription) takeReviewing() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]int, 0, len(s.reviewing))
+	for id := range s.reviewing {
+		ids = append(ids, id)
+	}
+	s.reviewing = nil
+	return ids
+}
+
+// matches — событие попадает хотя бы в одну тему клиента.
+func (s *subscription) matches(r *Route) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.active || r == nil {
+		return true
+	}
+	if r.Moderation {
+		return s.moderation
+	}
+	if s.moderation && r.MarkerID > 0 {
+		return true
+	}
+	if r.MarkerID > 0 && s.markers[r.MarkerID] {
+		return true
+	}
+	if r.DomainKey != "" && s.domains[r.DomainKey] {
+		return true
+	}
+	if s.viewport != nil && r.MarkerID > 0 {
+		// без координат (например, удалённая метка) отфильтровать нельзя — отдаём
+		return !r.HasLocation || s.viewport.contains(r.Lat, r.Lng)
+	}
+	return false
+}
+
+// SubscriptionState — текущие темы клиента (ответ на subscribe/unsubscribe).
+type SubscriptionState struct {
+	Viewport   *BBox    `json:"viewport,omitempty"`
+	MarkerIDs  []int    `json:"marker_ids"`
+	DomainKeys []string `json:"domain_keys"`
+	Moderation bool     `json:"moderation"`
+}
+
+func (s *subscription) state() SubscriptionState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := SubscriptionState{MarkerIDs: []int{}, DomainKeys: []string{}, Moderation: s.moderation}
+	if s.viewport != nil {
+		v := *s.viewport
+		st.Viewport = &v
+	}
+	for id := range s.markers {
+		st.MarkerIDs = append(st.MarkerIDs, id)
+	}
+	for k := range s.domains {
+		st.DomainKeys = append(st.DomainKeys, k)
+	}
+	return st
+}
+
+// clientMessage — сообщение клиента: {"action":"subscribe","topic":"viewport","bbox":{...}}.
+type clientMessage struct {
+	Action string   `json:"action"`
+	Topic  string   `json:"topic"`
+	BBox   *BBox    `json:"bbox,omitempty"`
+	IDs    []int    `json:"ids,omitempty"`
+	Keys   []string `json:"keys,omitempty"`
+	Since  int64    `json:"since,omitempty"`
+	// MarkerID — для review/release
+	MarkerID int `json:"marker_id,omitempty"`
+}
+
+// HandleMessage — разбор входящего сообщения клиента; ответ (subscribed или error) уходит только ему.
+func (c *Client) HandleMessage(raw []byte) {
+	var msg clientMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		c.reply(Event{Type: EventError, Payload: map[string]string{"error": "invalid message"}})
+		return
+	}
+	var err string
+	msg.Action = strings.ToLower(strings.TrimSpace(msg.Action))
+	switch msg.Action {
+	case "subscribe":
+		err = c.subscribe(msg)
+	case "unsubscribe":
+		err = c.unsubscribe(msg)
+	case "ping":
+		c.reply(Event{Type: EventPong})
+		return
+	case "resume":
+		// после подписок, чтобы повтор шёл уже по ним
+		ResumeClient(c, msg.Since)
+		return
+	case "review", "release":
+		c.handleReview(msg)
+		return
+	default:
+		err = "unknown action"
+	}
+	if err != "" {
+		c.reply(Event{Type: EventError, Payload: map[string]string{"error": err, "topic": msg.Topic}})
+		return
+	}
+	c.reply(Event{Type: EventSubscribed, Payload: c.subs.state()})
+}
+
+// Subscribe — подписка без сообщения клиента (SSE: темы из query string).
+func (c *Client) Subscribe(topic string, bbox *BBox, ids []int, keys []string) error {
+	if msg := c.subscribe(clientMessage{Topic: topic, BBox: bbox, IDs: ids, Keys: keys}); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func (c *Client) subscribe(msg clientMessage) string {
+	s := c.subs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch msg.Topic {
+	case TopicViewport:
+		if msg.BBox == nil || msg.BBox.SWLat > msg.BBox.NELat ||
+			msg.BBox.SWLat < -90 || msg.BBox.NELat > 90 {
+			return "invalid bbox"
+		}
+		b := *msg.BBox
+		s.viewport = &b
+	case TopicMarkers:
+		if s.markers == nil {
+			s.markers = map[int]bool{}
+		}
+		for _, id := range msg.IDs {
+			if id > 0 && len(s.markers) < maxSubscribedMarkers {
+				s.markers[id] = true
+			}
+		}
+	case TopicDomains:
+		if s.domains == nil {
+			s.domains = map[string]bool{}
+		}
+		for _, k := range msg.Keys {
+			if k = strings.TrimSpace(k); k != "" && len(s.domains) < maxSubscribedDomains {
+				s.domains[k] = true
+			}
+		}
+	case TopicModeration:
+		if !c.IsModerator {
+			return "moderators only"
+		}
+		s.moderation = true
+	default:
+		return "unknown topic"
+	}
+	s.active = true
+	return ""
+}
+
+// unsubscribe — без ids/keys тема снимается целиком.
+func (c *Client) unsubscribe(msg clientMessage) string {
+	s := c.subs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch msg.Topic {
+	case TopicViewport:
+		s.viewport = nil
+	case TopicMarkers:
+		if len(msg.IDs) == 0 {
+			s.markers = nil
+		}
+		for _, id := range msg.IDs {
+			delete(s.markers, id)
+		}
+	case TopicDomains:
+		if len(msg.Keys) == 0 {
+			s.domains = nil
+		}
+		for _, k := range msg.Keys {
+			delete(s.domains, strings.TrimSpace(k))
+		}
+	case TopicModeration:
+		s.moderation = false
+	default:
+		return "unknown topic"
+	}
+	// отписка от всего не возвращает клиента в режим «получать всё»
+	s.active = true
+	return ""
+}
+
+// handleReview — модератор открыл метку («review», повторяется для продления) или закрыл («release»).
+func (c *Client) handleReview(msg clientMessage) {
+	if !c.IsModerator {
+		c.reply(Event{Type: EventError, Payload: map[string]string{"error": "moderators only"}})
+		return
+	}
+	if msg.MarkerID <= 0 {
+		c.reply(Event{Type: EventError, Payload: map[string]string{"error": "marker_id required"}})
+		return
+	}
+	s := c.subs
+	s.mu.Lock()
+	if msg.Action == "release" {
+		delete(s.reviewing, msg.MarkerID)
+	} else {
+		if s.reviewing == nil {
+			s.reviewing = map[int]bool{}
+		}
+		s.reviewing[msg.MarkerID] = true
+	}
+	s.mu.Unlock()
+	if msg.Action == "release" {
+		releaseReview(msg.MarkerID, c.UserID, c.reviewHolder)
+		return
+	}
+	startReview(msg.MarkerID, c.UserID, c.Name, c.reviewHolder)
+}
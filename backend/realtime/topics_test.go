@@ -0,0 +1,72 @@
+package realtime
+
+import "testing"
+
+func TestSubscriptionMatches(t *testing.T) {
+	inside := &Route{MarkerID: 1, HasLocation: true, Lat: 55.75, Lng: 37.61, DomainKey: "roads"}
+	outside := &Route{MarkerID: 2, HasLocation: true, Lat: 59.93, Lng: 30.33, DomainKey: "parks"}
+	presence := &Route{Moderation: true}
+
+	legacy := &subscription{}
+	if !legacy.matches(inside) || !legacy.matches(outside) || !legacy.matches(presence) {
+		t.Fatal("client without subscriptions must receive everything")
+	}
+
+	c := &Client{subs: &subscription{}}
+	if errMsg := c.subscribe(clientMessage{Topic: TopicViewport, BBox: &BBox{SWLat: 55.5, SWLng: 37.3, NELat: 56, NELng: 37.9}}); errMsg != "" {
+		t.Fatal(errMsg)
+	}
+	if !c.subs.matches(inside) || c.subs.matches(outside) {
+		t.Error("viewport filter mismatch")
+	}
+	if c.subs.matches(presence) {
+		t.Error("moderation events must not reach map viewers")
+	}
+	if !c.subs.matches(&Route{MarkerID: 3}) {
+		t.Error("events without location must pass the viewport filter")
+	}
+
+	_ = c.subscribe(clientMessage{Topic: TopicDomains, Keys: []string{"parks"}})
+	if !c.subs.matches(outside) {
+		t.Error("domain topic should match outside the viewport")
+	}
+	_ = c.unsubscribe(clientMessage{Topic: TopicDomains})
+	_ = c.unsubscribe(clientMessage{Topic: TopicViewport})
+	if c.subs.matches(inside) {
+		t.Error("client unsubscribed from everything must not receive marker events")
+	}
+}
+
+func TestModerationTopicRequiresModerator(t *testing.T) {
+	c := &Client{subs: &subscription{}}
+	if c.subscribe(clientMessage{Topic: TopicModeration}) == "" {
+		t.Fatal("plain user subscribed to moderation")
+	}
+	c.IsModerator = true
+	if errMsg := c.subscribe(clientMessage{Topic: TopicModeration}); errMsg != "" {
+		t.Fatal(errMsg)
+	}
+	if !c.subs.matches(&Route{Moderation: true}) || !c.subs.matches(&Route{MarkerID: 9, HasLocation: true}) {
+		t.Error("moderation topic should receive presence and marker events")
+	}
+}
+
+func TestBBoxAntimeridian(t *testing.T) {
+	b := BBox{SWLat: 60, SWLng: 170, NELat: 70, NELng: -170}
+	if !b.contains(65, 179) || !b.contains(65, -175) || b.contains(65, 0) {
+		t.Error("antimeridian bbox mismatch")
+	}
+}
+
+func TestPrivateMarkerEventsReachModeratorsAndOwner(t *testing.T) {
+	out := outbound{route: &Route{MarkerID: 1, Private: true, OwnerID: 7}}
+	if out.matches(NewClient(5, false, nil)) {
+		t.Error("unpublished marker event must not reach other users")
+	}
+	if !out.matches(NewClient(7, false, nil)) {
+		t.Error("author should see events of their own marker")
+	}
+	if !out.matches(NewClient(9, true, nil)) {
+		t.Error("moderators see unpublished markers")
+	}
+}
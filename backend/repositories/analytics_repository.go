@@ -39,13 +39,13 @@ func GetAnalyticsDashboard(days int) (*AnalyticsDashboard, error) {
 	_ = database.DB.QueryRow(`SELECT COUNT(*) FROM markers`).Scan(&d.Total)
 	_ = database.DB.QueryRow(`
 		SELECT COUNT(*) FROM markers
-		WHERE LOWER(COALESCE(status,'pending')) IN ('approved','in_progress')`).Scan(&d.Active)
+		WHERE LOWER(COALESCE(status,'pending')) IN ('approved','in_progress','reopened')`).Scan(&d.Active)
 	_ = database.DB.QueryRow(`
 		SELECT COUNT(*) FROM markers WHERE LOWER(COALESCE(status,'')) = 'resolved'`).Scan(&d.Resolved)
 	_ = database.DB.QueryRow(`
 		SELECT COUNT(*) FROM markers WHERE
 		(LOWER(COALESCE(status,'pending')) = 'pending' AND response_due_at < NOW())
-		OR (LOWER(COALESCE(status,'')) IN ('approved','in_progress') AND resolution_due_at < NOW())`).Scan(&d.Overdue)
+		OR (LOWER(COALESCE(status,'')) IN ('approved','in_progress','reopened') AND resolution_due_at < NOW() AND sla_paused_at IS NULL)`).Scan(&d.Overdue)
 
 	rows, err := database.DB.Query(`
 		SELECT to_char(created_at::date, 'YYYY-MM-DD') AS d, COUNT(*)::int
@@ -88,6 +88,8 @@ func GetAnalyticsDashboard(days int) (*AnalyticsDashboard, error) {
 		  AND resolved_at > resolution_due_at`).Scan(&late)
 	d.SLA["on_time"] = onTime
 	d.SLA["late"] = late
+	// resolution_due_at уже сдвинут на время пауз, поэтому on_time/late их учитывают
+	d.SLA["paused_now"], d.SLA["paused_hours_total"] = SLAPauseTotals()
 	return d, nil
 }
 
@@ -202,7 +204,7 @@ func LeaderboardByPeriod(period string, limit int) ([]map[string]interface{}, er
 		FROM markers m
 		JOIN users u ON u.id = m.user_id
 		WHERE m.created_at >= NOW() - $1::interval
-		  AND LOWER(COALESCE(m.status,'')) IN ('approved','resolved','in_progress')
+		  AND LOWER(COALESCE(m.status,'')) IN ('approved','resolved','in_progress','reopened')
 		GROUP BY u.id, u.email, u.display_name, u.avatar_url
 		ORDER BY cnt DESC, u.karma_points DESC
 		LIMIT $2`, interval, limit)
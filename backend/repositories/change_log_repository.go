@@ -4,6 +4,8 @@ import (
 	"backend/database"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type TimelineEntry struct {
@@ -20,12 +22,23 @@ type TimelineEntry struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// dbExecutor — *sql.DB или *sql.Tx: запись журнала и пересчёт сроков внутри чужой транзакции.
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 func InsertMarkerChange(markerID int, field, oldVal, newVal string, actorID *int) error {
+	return insertMarkerChange(database.DB, markerID, field, oldVal, newVal, actorID)
+}
+
+func insertMarkerChange(q dbExecutor, markerID int, field, oldVal, newVal string, actorID *int) error {
 	var actor sql.NullInt64
 	if actorID != nil && *actorID > 0 {
 		actor = sql.NullInt64{Int64: int64(*actorID), Valid: true}
 	}
-	_, err := database.DB.Exec(`
+	_, err := q.Exec(`
 		INSERT INTO marker_change_log (marker_id, field_name, old_value, new_value, actor_user_id)
 		VALUES ($1, $2, $3, $4, $5)`,
 		markerID, field, nullStr(oldVal), nullStr(newVal), actor,
@@ -33,6 +46,34 @@ func InsertMarkerChange(markerID int, field, oldVal, newVal string, actorID *int
 	return err
 }
 
+// markerChange — одна запись marker_change_log для пакетной вставки.
+type markerChange struct {
+	markerID       int
+	field          string
+	oldVal, newVal string
+}
+
+// insertMarkerChanges — пачка изменений одним INSERT (массовые переносы внутри транзакции).
+func insertMarkerChanges(q dbExecutor, changes []markerChange, actorID *int) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	ids := make([]int, len(changes))
+	fields := make([]string, len(changes))
+	oldVals := make([]string, len(changes))
+	newVals := make([]string, len(changes))
+	for i, c := range changes {
+		ids[i], fields[i], oldVals[i], newVals[i] = c.markerID, c.field, c.oldVal, c.newVal
+	}
+	_, err := q.Exec(`
+		INSERT INTO marker_change_log (marker_id, field_name, old_value, new_value, actor_user_id)
+		SELECT c.id, c.field, NULLIF(c.old_value, ''), NULLIF(c.new_value, ''), $5
+		FROM unnest($1::bigint[], $2::text[], $3::text[], $4::text[]) AS c(id, field, old_value, new_value)`,
+		int64Array(ids), pq.Array(fields), pq.Array(oldVals), pq.Array(newVals), nullActor(actorID),
+	)
+	return err
+}
+
 func ListMarkerTimeline(markerID int, limit int) ([]TimelineEntry, error) {
 	if limit < 1 || limit > 200 {
 		limit = 100
@@ -0,0 +1,282 @@
+package repositories
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"backend/database"
+)
+
+// reviewedMarkerSQL — метка прошла модерацию или её категорию явно разметил модератор,
+// поэтому domain_key считается итоговым ответом для сравнения с ai_domain_key.
+const reviewedMarkerSQL = `(LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'resolved', 'reopened')
+	OR EXISTS (SELECT 1 FROM classification_labels l WHERE l.marker_id = m.id))`
+
+// ConfusionCell — сколько меток ИИ отнёс к AIDomainKey при итоговой категории DomainKey.
+type ConfusionCell struct {
+	AIDomainKey string `json:"ai_domain_key"`
+	DomainKey   string `json:"domain_key"`
+	Count       int    `json:"count"`
+}
+
+// DomainQuality — precision/recall ответа ИИ по одному направлению.
+type DomainQuality struct {
+	DomainKey string  `json:"domain_key"`
+	Label     string  `json:"label"`
+	Predicted int     `json:"predicted"`
+	Actual    int     `json:"actual"`
+	Correct   int     `json:"correct"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// CalibrationBucket — корзина уверенности [From, To): насколько заявленная уверенность совпадает с точностью.
+type CalibrationBucket struct {
+	From          float64 `json:"from"`
+	To            float64 `json:"to"`
+	Count         int     `json:"count"`
+	AvgConfidence float64 `json:"avg_confidence"`
+	Accuracy      float64 `json:"accuracy"`
+}
+
+// LowConfidenceMarker — незакрытая метка, в категории которой классификатор не уверен.
+type LowConfidenceMarker struct {
+	ID           int       `json:"id"`
+	Text         string    `json:"text"`
+	Status       string    `json:"status"`
+	DomainKey    string    `json:"domain_key"`
+	AIDomainKey  string    `json:"ai_domain_key"`
+	AIConfidence float64   `json:"ai_confidence"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ClassificationQuality struct {
+	Days      int     `json:"days"`
+	Threshold float64 `json:"threshold"`
+	Evaluated int     `json:"evaluated"`
+	Correct   int     `json:"correct"`
+	Accuracy  float64 `json:"accuracy"`
+	MacroF1   float64 `json:"macro_f1"`
+	// ECE — средневзвешенный разрыв между уверенностью и точностью по корзинам
+	ECE         float64             `json:"ece"`
+	Domains     []DomainQuality     `json:"domains"`
+	Confusion   []ConfusionCell     `json:"confusion"`
+	Calibration []CalibrationBucket `json:"calibration"`
+	// LowConfidenceByDomain — размер очереди низкой уверенности по текущему domain_key
+	LowConfidenceByDomain map[string]int        `json:"low_confidence_by_domain"`
+	LowConfidence         []LowConfidenceMarker `json:"low_confidence"`
+}
+
+// GetClassificationQuality сравнивает ai_domain_key с итоговым domain_key проверенных меток за days дней
+// (0 — за всё время) и собирает очередь открытых меток с ai_confidence ниже threshold.
+func GetClassificationQuality(days int, threshold float64, limit int) (*ClassificationQuality, error) {
+	if days < 0 || days > 365 {
+		days = 90
+	}
+	if threshold <= 0 || threshold >= 1 {
+		threshold = 0.5
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	q := &ClassificationQuality{Days: days, Threshold: threshold, LowConfidenceByDomain: map[string]int{}}
+
+	rows, err := database.DB.Query(`
+		SELECT m.ai_domain_key, m.domain_key, COUNT(*)::int
+		FROM markers m
+		WHERE NULLIF(TRIM(m.ai_domain_key), '') IS NOT NULL AND NULLIF(TRIM(m.domain_key), '') IS NOT NULL
+		  AND ($1 = 0 OR m.created_at >= NOW() - ($1 || ' days')::interval)
+		  AND `+reviewedMarkerSQL+`
+		GROUP BY 1, 2 ORDER BY 3 DESC`, days)
+	if err != nil {
+		return nil, err
+	}
+	q.Confusion = []ConfusionCell{}
+	for rows.Next() {
+		var c ConfusionCell
+		if err := rows.Scan(&c.AIDomainKey, &c.DomainKey, &c.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		q.Confusion = append(q.Confusion, c)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+	rows, err = database.DB.Query(`SELECT domain_key, label_ru FROM classification_domains`)
+	if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{}
+	for rows.Next() {
+		var k, l string
+		if err := rows.Scan(&k, &l); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		labels[k] = l
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+	q.Domains, q.Evaluated, q.Correct = domainQualityFromConfusion(q.Confusion, labels)
+	if q.Evaluated > 0 {
+		q.Accuracy = float64(q.Correct) / float64(q.Evaluated)
+	}
+	q.MacroF1 = macroF1(q.Domains)
+
+	rows, err = database.DB.Query(`
+		SELECT LEAST(FLOOR(m.ai_confidence * 10), 9)::int AS b, COUNT(*)::int,
+		       AVG(m.ai_confidence), AVG(CASE WHEN m.ai_domain_key = m.domain_key THEN 1.0 ELSE 0.0 END)
+		FROM markers m
+		WHERE m.ai_confidence IS NOT NULL AND m.ai_confidence >= 0
+		  AND NULLIF(TRIM(m.ai_domain_key), '') IS NOT NULL AND NULLIF(TRIM(m.domain_key), '') IS NOT NULL
+		  AND ($1 = 0 OR m.created_at >= NOW() - ($1 || ' days')::interval)
+		  AND `+reviewedMarkerSQL+`
+		GROUP BY 1 ORDER BY 1`, days)
+	if err != nil {
+		return nil, err
+	}
+	q.Calibration = []CalibrationBucket{}
+	for rows.Next() {
+		var b int
+		var cb CalibrationBucket
+		if err := rows.Scan(&b, &cb.Count, &cb.AvgConfidence, &cb.Accuracy); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		cb.From, cb.To = float64(b)/10, float64(b+1)/10
+		q.Calibration = append(q.Calibration, cb)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+	q.ECE = expectedCalibrationError(q.Calibration)
+
+	const lowConfWhere = `m.ai_confidence IS NOT NULL AND m.ai_confidence < $1
+		  AND LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('pending', 'approved', 'in_progress', 'reopened')
+		  AND NOT EXISTS (SELECT 1 FROM classification_labels l WHERE l.marker_id = m.id)`
+	rows, err = database.DB.Query(`
+		SELECT COALESCE(m.domain_key, ''), COUNT(*)::int FROM markers m
+		WHERE `+lowConfWhere+` GROUP BY 1`, threshold)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var k string
+		var n int
+		if err := rows.Scan(&k, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		q.LowConfidenceByDomain[k] = n
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = database.DB.Query(`
+		SELECT m.id, COALESCE(m.text, ''), LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')),
+		       COALESCE(m.domain_key, ''), COALESCE(m.ai_domain_key, ''), m.ai_confidence, m.created_at
+		FROM markers m
+		WHERE `+lowConfWhere+`
+		ORDER BY m.ai_confidence ASC, m.created_at ASC
+		LIMIT $2`, threshold, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	q.LowConfidence = []LowConfidenceMarker{}
+	for rows.Next() {
+		var lm LowConfidenceMarker
+		if err := rows.Scan(&lm.ID, &lm.Text, &lm.Status, &lm.DomainKey, &lm.AIDomainKey, &lm.AIConfidence, &lm.CreatedAt); err != nil {
+			return nil, err
+		}
+		q.LowConfidence = append(q.LowConfidence, lm)
+	}
+	return q, rows.Err()
+}
+
+// closeRows — закрыть выборку и вернуть ошибку чтения, если строки оборвались на середине.
+func closeRows(rows *sql.Rows) error {
+	err := rows.Err()
+	rows.Close()
+	return err
+}
+
+// macroF1 — среднее F1 по направлениям.
+func macroF1(domains []DomainQuality) float64 {
+	if len(domains) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, d := range domains {
+		sum += d.F1
+	}
+	return sum / float64(len(domains))
+}
+
+// expectedCalibrationError — разрыв |уверенность − точность| по корзинам, взвешенный числом меток.
+func expectedCalibrationError(buckets []CalibrationBucket) float64 {
+	total := 0
+	for _, cb := range buckets {
+		total += cb.Count
+	}
+	if total == 0 {
+		return 0
+	}
+	var ece float64
+	for _, cb := range buckets {
+		ece += float64(cb.Count) / float64(total) * math.Abs(cb.AvgConfidence-cb.Accuracy)
+	}
+	return ece
+}
+
+// domainQualityFromConfusion считает precision/recall/F1 по каждому направлению, встречающемуся в матрице.
+func domainQualityFromConfusion(cells []ConfusionCell, labels map[string]string) ([]DomainQuality, int, int) {
+	byKey := map[string]*DomainQuality{}
+	get := func(k string) *DomainQuality {
+		d, ok := byKey[k]
+		if !ok {
+			d = &DomainQuality{DomainKey: k, Label: labels[k]}
+			if d.Label == "" {
+				d.Label = k
+			}
+			byKey[k] = d
+		}
+		return d
+	}
+	evaluated, correct := 0, 0
+	for _, c := range cells {
+		evaluated += c.Count
+		get(c.AIDomainKey).Predicted += c.Count
+		get(c.DomainKey).Actual += c.Count
+		if c.AIDomainKey == c.DomainKey {
+			get(c.DomainKey).Correct += c.Count
+			correct += c.Count
+		}
+	}
+	out := make([]DomainQuality, 0, len(byKey))
+	for _, d := range byKey {
+		if d.Predicted > 0 {
+			d.Precision = float64(d.Correct) / float64(d.Predicted)
+		}
+		if d.Actual > 0 {
+			d.Recall = float64(d.Correct) / float64(d.Actual)
+		}
+		if d.Precision+d.Recall > 0 {
+			d.F1 = 2 * d.Precision * d.Recall / (d.Precision + d.Recall)
+		}
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Actual != out[j].Actual {
+			return out[i].Actual > out[j].Actual
+		}
+		return out[i].DomainKey < out[j].DomainKey
+	})
+	return out, evaluated, correct
+}
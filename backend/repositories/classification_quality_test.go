@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"math"
+	"testing"
+)
+
+func approxEqual(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestDomainQualityFromConfusion(t *testing.T) {
+	cells := []ConfusionCell{
+		{AIDomainKey: "roads", DomainKey: "roads", Count: 8},
+		{AIDomainKey: "roads", DomainKey: "parks", Count: 2},
+		{AIDomainKey: "parks", DomainKey: "parks", Count: 3},
+		// social ни разу не был итоговой категорией (нет поддержки), transit ни разу не предсказан
+		{AIDomainKey: "social", DomainKey: "parks", Count: 1},
+		{AIDomainKey: "roads", DomainKey: "transit", Count: 4},
+	}
+	domains, evaluated, correct := domainQualityFromConfusion(cells, map[string]string{"roads": "Дороги"})
+	if evaluated != 18 || correct != 11 {
+		t.Fatalf("evaluated/correct = %d/%d, want 18/11", evaluated, correct)
+	}
+	byKey := map[string]DomainQuality{}
+	for _, d := range domains {
+		byKey[d.DomainKey] = d
+	}
+
+	cases := []struct {
+		key                   string
+		predicted, actual     int
+		precision, recall, f1 float64
+	}{
+		{"roads", 14, 8, 8.0 / 14, 1, 2 * (8.0 / 14) / (8.0/14 + 1)},
+		{"parks", 3, 6, 1, 0.5, 2 * 0.5 / 1.5},
+		{"social", 1, 0, 0, 0, 0},
+		{"transit", 0, 4, 0, 0, 0},
+	}
+	for _, tc := range cases {
+		d, ok := byKey[tc.key]
+		if !ok {
+			t.Fatalf("%s missing from %+v", tc.key, domains)
+		}
+		if d.Predicted != tc.predicted || d.Actual != tc.actual {
+			t.Errorf("%s predicted/actual = %d/%d, want %d/%d", tc.key, d.Predicted, d.Actual, tc.predicted, tc.actual)
+		}
+		if !approxEqual(d.Precision, tc.precision) || !approxEqual(d.Recall, tc.recall) || !approxEqual(d.F1, tc.f1) {
+			t.Errorf("%s p/r/f1 = %v/%v/%v, want %v/%v/%v", tc.key, d.Precision, d.Recall, d.F1, tc.precision, tc.recall, tc.f1)
+		}
+		if math.IsNaN(d.Precision) || math.IsNaN(d.Recall) || math.IsNaN(d.F1) {
+			t.Errorf("%s has NaN metrics: %+v", tc.key, d)
+		}
+	}
+	if byKey["roads"].Label != "Дороги" || byKey["parks"].Label != "parks" {
+		t.Errorf("labels = %q, %q", byKey["roads"].Label, byKey["parks"].Label)
+	}
+	// сортировка: больше поддержка — выше, при равной — по ключу
+	if domains[0].DomainKey != "roads" || domains[len(domains)-1].DomainKey != "social" {
+		t.Errorf("unexpected order %+v", domains)
+	}
+}
+
+func TestDomainQualityFromConfusionEmpty(t *testing.T) {
+	domains, evaluated, correct := domainQualityFromConfusion(nil, nil)
+	if len(domains) != 0 || evaluated != 0 || correct != 0 {
+		t.Fatalf("got %+v %d %d", domains, evaluated, correct)
+	}
+	if macroF1(domains) != 0 {
+		t.Fatal("macro F1 of no domains must be 0")
+	}
+}
+
+func TestMacroF1(t *testing.T) {
+	got := macroF1([]DomainQuality{{F1: 1}, {F1: 0.5}, {F1: 0}})
+	if !approxEqual(got, 0.5) {
+		t.Fatalf("macro F1 = %v, want 0.5", got)
+	}
+}
+
+func TestExpectedCalibrationError(t *testing.T) {
+	cases := []struct {
+		name    string
+		buckets []CalibrationBucket
+		want    float64
+	}{
+		{"no buckets", nil, 0},
+		{"empty buckets", []CalibrationBucket{{From: 0.5, To: 0.6}}, 0},
+		{"perfectly calibrated", []CalibrationBucket{
+			{From: 0.2, To: 0.3, Count: 10, AvgConfidence: 0.25, Accuracy: 0.25},
+			{From: 0.9, To: 1, Count: 30, AvgConfidence: 0.95, Accuracy: 0.95},
+		}, 0},
+		{"overconfident high bucket", []CalibrationBucket{
+			{From: 0.2, To: 0.3, Count: 10, AvgConfidence: 0.25, Accuracy: 0.35},
+			{From: 0.9, To: 1, Count: 30, AvgConfidence: 0.95, Accuracy: 0.55},
+		}, 0.25*0.1 + 0.75*0.4},
+	}
+	for _, tc := range cases {
+		if got := expectedCalibrationError(tc.buckets); !approxEqual(got, tc.want) {
+			t.Errorf("%s: ECE = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
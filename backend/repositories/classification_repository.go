@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/database"
+)
+
+// ClassificationDisagreement — клиент прислал другую категорию/уверенность, чем посчитал сервер.
+type ClassificationDisagreement struct {
+	MarkerID         int
+	ClientDomainKey  string
+	ClientIssueKey   string
+	ClientConfidence *float64
+	ServerDomainKey  string
+	ServerIssueKey   string
+	ServerConfidence *float64
+	ServerSource     string
+}
+
+func InsertClassificationDisagreement(d ClassificationDisagreement) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO classification_disagreements
+			(marker_id, client_domain_key, client_issue_key, client_confidence,
+			 server_domain_key, server_issue_key, server_confidence, server_source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		d.MarkerID, nullStr(d.ClientDomainKey), nullStr(d.ClientIssueKey), d.ClientConfidence,
+		nullStr(d.ServerDomainKey), nullStr(d.ServerIssueKey), d.ServerConfidence, nullStr(d.ServerSource),
+	)
+	return err
+}
+
+// Источники разметки для обучения классификатора.
+const (
+	LabelModeratorChange    = "moderator_change"
+	LabelAIConfirmed        = "ai_confirmed"
+	LabelSuggestionAccepted = "suggestion_accepted"
+)
+
+var ErrUnknownDomain = errors.New("unknown domain_key")
+var ErrNoAIClassification = errors.New("marker has no AI classification")
+
+// ClassificationLabel — одна строка разметки: текст обращения и категория, которую подтвердил модератор.
+type ClassificationLabel struct {
+	ID                int       `json:"id"`
+	MarkerID          int       `json:"marker_id"`
+	Text              string    `json:"text"`
+	DomainKey         string    `json:"domain_key"`
+	PreviousDomainKey string    `json:"previous_domain_key,omitempty"`
+	AIDomainKey       string    `json:"ai_domain_key,omitempty"`
+	AIConfidence      *float64  `json:"ai_confidence,omitempty"`
+	AICorrect         *bool     `json:"ai_correct,omitempty"`
+	Source            string    `json:"source"`
+	LabeledBy         *int      `json:"labeled_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// recordClassificationLabel сохраняет текущую категорию метки как эталон.
+// Если aiDomainKey пуст, ответом ИИ считается серверная классификация при создании (ai_domain_key/ai_confidence).
+func recordClassificationLabel(markerID int, previousDomainKey, source, aiDomainKey string, aiConfidence *float64, actorID *int) error {
+	return recordClassificationLabelWith(database.DB, markerID, previousDomainKey, source, aiDomainKey, aiConfidence, actorID)
+}
+
+func recordClassificationLabelWith(q dbExecutor, markerID int, previousDomainKey, source, aiDomainKey string, aiConfidence *float64, actorID *int) error {
+	_, err := q.Exec(`
+		INSERT INTO classification_labels
+			(marker_id, text, domain_key, previous_domain_key, ai_domain_key, ai_confidence, ai_correct, source, labeled_by)
+		SELECT m.id, m.text, m.domain_key, $2, ai.domain_key, ai.confidence,
+		       CASE WHEN ai.domain_key IS NULL THEN NULL ELSE ai.domain_key = m.domain_key END,
+		       $5, $6
+		FROM markers m
+		CROSS JOIN LATERAL (
+			SELECT COALESCE($3, NULLIF(TRIM(m.ai_domain_key), '')) AS domain_key,
+			       CASE WHEN $3::text IS NULL THEN m.ai_confidence ELSE $4 END AS confidence
+		) ai
+		WHERE m.id = $1 AND NULLIF(TRIM(m.domain_key), '') IS NOT NULL AND TRIM(COALESCE(m.text, '')) <> ''`,
+		markerID, nullStr(previousDomainKey), nullStr(aiDomainKey), aiConfidence, source, nullActor(actorID),
+	)
+	return err
+}
+
+// SetMarkerClassification — модератор выставляет категорию метки. Всё в одной транзакции: смена domain_key
+// пишется в marker_change_log, назначенное ведомство переходит к ведомству нового направления, сроки решения
+// пересчитываются, отложенное предложение повторной классификации снимается. Возвращает прежний domain_key.
+func SetMarkerClassification(markerID int, domainKey, groupKey, issueKey, source string, actorID *int) (string, error) {
+	domainKey = strings.TrimSpace(domainKey)
+	groupKey, issueKey = strings.TrimSpace(groupKey), strings.TrimSpace(issueKey)
+	if domainKey == "" {
+		return "", ErrUnknownDomain
+	}
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	if err := validateMarkerTaxonomyWith(tx, domainKey, groupKey, issueKey); err != nil {
+		return "", err
+	}
+	var old string
+	var oldDept, newDept int
+	err = tx.QueryRow(`
+		WITH cur AS (SELECT id, domain_key, department_id FROM markers WHERE id = $1 FOR UPDATE)
+		UPDATE markers m SET
+			domain_key = $2, group_key = $3, issue_key = $4,
+			department_id = `+reassignedDepartmentSQL("$2::text")+`,
+			suggested_domain_key = NULL, suggested_group_key = NULL, suggested_issue_key = NULL,
+			suggested_confidence = NULL, suggested_at = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		FROM cur WHERE m.id = cur.id
+		RETURNING COALESCE(cur.domain_key, ''), COALESCE(cur.department_id, 0), COALESCE(m.department_id, 0)`,
+		markerID, domainKey, nullStr(groupKey), nullStr(issueKey),
+	).Scan(&old, &oldDept, &newDept)
+	if err != nil {
+		return "", err
+	}
+	if old != domainKey {
+		if err := insertMarkerChange(tx, markerID, "domain_key", old, domainKey, actorID); err != nil {
+			return old, err
+		}
+		if oldDept != newDept {
+			if err := insertMarkerChange(tx, markerID, "department_id", itoaOrEmpty(oldDept), itoaOrEmpty(newDept), actorID); err != nil {
+				return old, err
+			}
+		}
+		if _, err := recomputeSLADueDatesWith(tx, actorID, []int{markerID}); err != nil {
+			return old, err
+		}
+	}
+	if err := recordClassificationLabelWith(tx, markerID, old, source, "", nil, actorID); err != nil {
+		return old, err
+	}
+	return old, tx.Commit()
+}
+
+// ConfirmMarkerAIClassification — модератор подтверждает ответ классификатора: категория метки
+// приводится к ai_domain_key/ai_group_key/ai_issue_key (если отличалась), в разметку пишется ai_confirmed.
+// Если группы или проблемы из ответа уже нет в дереве, подтверждается только направление (как при создании метки).
+func ConfirmMarkerAIClassification(markerID int, actorID *int) (string, error) {
+	var domainKey, groupKey, issueKey sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT NULLIF(TRIM(ai_domain_key), ''), ai_group_key, ai_issue_key FROM markers WHERE id = $1`, markerID,
+	).Scan(&domainKey, &groupKey, &issueKey)
+	if err != nil {
+		return "", err
+	}
+	if !domainKey.Valid {
+		return "", ErrNoAIClassification
+	}
+	group, issue := groupKey.String, issueKey.String
+	if err := ValidateMarkerTaxonomy(domainKey.String, group, issue); err != nil {
+		if !IsStaleTaxonomyError(err) {
+			return "", err
+		}
+		// классификатор мог ответить по старой таксономии; пропавшее направление — по-прежнему ошибка
+		if !errors.Is(err, ErrUnknownDomain) {
+			group, issue = "", ""
+		}
+	}
+	return SetMarkerClassification(markerID, domainKey.String, group, issue, LabelAIConfirmed, actorID)
+}
+
+// ClassificationLabelFilter — выборка для экспорта; пустые поля не ограничивают.
+type ClassificationLabelFilter struct {
+	Since     *time.Time
+	DomainKey string
+}
+
+// ListClassificationLabelsForExport — последняя разметка по каждой метке. Категории, которых уже нет
+// в classification_domains, пропускаются: train.py обучает только на актуальной таксономии.
+func ListClassificationLabelsForExport(f ClassificationLabelFilter) ([]ClassificationLabel, error) {
+	var since interface{}
+	if f.Since != nil {
+		since = *f.Since
+	}
+	rows, err := database.DB.Query(`
+		SELECT id, marker_id, text, domain_key, COALESCE(previous_domain_key, ''), COALESCE(ai_domain_key, ''),
+		       ai_confidence, ai_correct, source, labeled_by, created_at
+		FROM (
+			SELECT DISTINCT ON (l.marker_id) l.*
+			FROM classification_labels l
+			ORDER BY l.marker_id, l.created_at DESC, l.id DESC
+		) l
+		WHERE ($1::timestamp IS NULL OR l.created_at >= $1)
+		  AND ($2 = '' OR l.domain_key = $2)
+		  AND EXISTS (SELECT 1 FROM classification_domains c WHERE c.domain_key = l.domain_key)
+		ORDER BY l.created_at ASC, l.id ASC`, since, strings.TrimSpace(f.DomainKey))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []ClassificationLabel{}
+	for rows.Next() {
+		var l ClassificationLabel
+		var conf sql.NullFloat64
+		var correct sql.NullBool
+		var by sql.NullInt64
+		if rows.Scan(&l.ID, &l.MarkerID, &l.Text, &l.DomainKey, &l.PreviousDomainKey, &l.AIDomainKey,
+			&conf, &correct, &l.Source, &by, &l.CreatedAt) != nil {
+			continue
+		}
+		if conf.Valid {
+			v := conf.Float64
+			l.AIConfidence = &v
+		}
+		if correct.Valid {
+			v := correct.Bool
+			l.AICorrect = &v
+		}
+		if by.Valid {
+			v := int(by.Int64)
+			l.LabeledBy = &v
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
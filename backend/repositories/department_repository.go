@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"backend/database"
+)
+
+// DepartmentRepIDsForMarker — представители назначенного ведомства метки, а пока оно не назначено —
+// ведомств, отвечающих за направление (departments.category_keys).
+func DepartmentRepIDsForMarker(markerID int) ([]int, error) {
+	rows, err := database.DB.Query(`
+		SELECT DISTINCT u.id
+		FROM markers m
+		JOIN departments d ON (m.department_id IS NOT NULL AND d.id = m.department_id)
+		                   OR (m.department_id IS NULL AND m.domain_key = ANY(d.category_keys))
+		JOIN users u ON u.department_id = d.id
+		WHERE m.id = $1 AND COALESCE(u.is_department_rep, FALSE)`, markerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// DepartmentIDForDomain — ведомство, отвечающее за направление (0 — не найдено).
+func DepartmentIDForDomain(domainKey string) int {
+	if domainKey == "" {
+		return 0
+	}
+	var id int
+	if err := database.DB.QueryRow(
+		`SELECT id FROM departments WHERE $1 = ANY(category_keys) ORDER BY id LIMIT 1`, domainKey,
+	).Scan(&id); err != nil {
+		return 0
+	}
+	return id
+}
+
+// DepartmentRepIDs — представители конкретного ведомства.
+func DepartmentRepIDs(departmentID int) ([]int, error) {
+	rows, err := database.DB.Query(`
+		SELECT id FROM users
+		WHERE department_id = $1 AND COALESCE(is_department_rep, FALSE)
+		ORDER BY id`, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
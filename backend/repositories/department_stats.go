@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"backend/database"
+)
+
+// DepartmentSLAStats — сводка по меткам, назначенным ведомству.
+type DepartmentSLAStats struct {
+	DepartmentID      int            `json:"department_id"`
+	ByStatus          map[string]int `json:"by_status"`
+	Active            int            `json:"active"`
+	Overdue           int            `json:"overdue"`
+	PausedNow         int            `json:"paused_now"`
+	OnTime            int            `json:"on_time"`
+	Late              int            `json:"late"`
+	AvgResolutionDays *float64       `json:"avg_resolution_days,omitempty"`
+}
+
+func GetDepartmentSLAStats(departmentID int) (*DepartmentSLAStats, error) {
+	s := &DepartmentSLAStats{DepartmentID: departmentID, ByStatus: map[string]int{}}
+	rows, err := database.DB.Query(`
+		SELECT LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending')), COUNT(*)::int
+		FROM markers WHERE department_id = $1
+		GROUP BY 1`, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var st string
+		var n int
+		if rows.Scan(&st, &n) == nil {
+			s.ByStatus[st] = n
+		}
+	}
+	s.Active = s.ByStatus["approved"] + s.ByStatus["in_progress"] + s.ByStatus["reopened"]
+
+	var avg sql.NullFloat64
+	err = database.DB.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE `+overdueSQL("m")+`)::int,
+			COUNT(*) FILTER (WHERE m.sla_paused_at IS NOT NULL)::int,
+			COUNT(*) FILTER (WHERE m.resolved_at IS NOT NULL AND m.resolution_due_at IS NOT NULL
+			                   AND m.resolved_at <= m.resolution_due_at)::int,
+			COUNT(*) FILTER (WHERE m.resolved_at IS NOT NULL AND m.resolution_due_at IS NOT NULL
+			                   AND m.resolved_at > m.resolution_due_at)::int,
+			AVG(EXTRACT(EPOCH FROM m.resolved_at - m.resolution_sla_from) / 86400.0)
+				FILTER (WHERE m.resolved_at IS NOT NULL AND m.resolution_sla_from IS NOT NULL)
+		FROM markers m WHERE m.department_id = $1`, departmentID,
+	).Scan(&s.Overdue, &s.PausedNow, &s.OnTime, &s.Late, &avg)
+	if err != nil {
+		return nil, err
+	}
+	if avg.Valid {
+		v := avg.Float64
+		s.AvgResolutionDays = &v
+	}
+	return s, nil
+}
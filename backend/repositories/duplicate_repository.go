@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"database/sql"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	defaultDuplicateThreshold = 0.6
+	defaultDuplicateRadiusM   = 300
+)
+
+// DuplicateSettings — порог оценки и радиус поиска дублей для направления.
+type DuplicateSettings struct {
+	Threshold float64 `json:"threshold"`
+	RadiusM   int     `json:"radius_m"`
+}
+
+func defaultDuplicateSettings() DuplicateSettings {
+	s := DuplicateSettings{Threshold: defaultDuplicateThreshold, RadiusM: defaultDuplicateRadiusM}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("DUPLICATE_THRESHOLD")), 64); err == nil && v > 0 && v <= 1 {
+		s.Threshold = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("DUPLICATE_RADIUS_M"))); err == nil && v > 0 {
+		s.RadiusM = v
+	}
+	return s
+}
+
+// DuplicateSettingsForDomain — настройки направления, незаданные поля берутся по умолчанию.
+func DuplicateSettingsForDomain(domainKey string) DuplicateSettings {
+	s := defaultDuplicateSettings()
+	if strings.TrimSpace(domainKey) == "" {
+		return s
+	}
+	var threshold sql.NullFloat64
+	var radius sql.NullInt64
+	if err := database.DB.QueryRow(
+		`SELECT duplicate_threshold, duplicate_radius_m FROM classification_domains WHERE domain_key = $1`,
+		strings.TrimSpace(domainKey),
+	).Scan(&threshold, &radius); err != nil {
+		return s
+	}
+	if threshold.Valid && threshold.Float64 > 0 && threshold.Float64 <= 1 {
+		s.Threshold = threshold.Float64
+	}
+	if radius.Valid && radius.Int64 > 0 {
+		s.RadiusM = int(radius.Int64)
+	}
+	return s
+}
+
+// FindDuplicateCandidates — открытые обращения в радиусе направления, оценённые по расстоянию, направлению
+// и похожести текста (pg_trgm, если установлен). Возвращаются кандидаты с оценкой не ниже порога направления.
+func FindDuplicateCandidates(text, domainKey string, lat, lng float64, limit int) ([]models.DuplicateCandidate, DuplicateSettings, error) {
+	if limit <= 0 || limit > 20 {
+		limit = 5
+	}
+	settings := DuplicateSettingsForDomain(domainKey)
+	// bbox под индекс; градус долготы короче на широте lat
+	delta := float64(settings.RadiusM) / 111000
+	lngDelta := delta / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	args := []interface{}{lat - delta, lat + delta, lng - lngDelta, lng + lngDelta}
+	simExpr := "NULL::float8"
+	if database.TrigramAvailable() {
+		args = append(args, text)
+		simExpr = "similarity(COALESCE(m.text, ''), $5)::float8"
+	}
+	rows, err := database.DB.Query(`
+		SELECT m.id, COALESCE(m.text, ''), COALESCE(m.domain_key, ''), COALESCE(m.status, 'pending'),
+		       m.latitude, m.longitude,
+		       (SELECT COUNT(*)::int FROM marker_supports s WHERE s.marker_id = m.id),
+		       `+simExpr+`
+		FROM markers m
+		WHERE LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened', 'pending')
+		  AND m.latitude BETWEEN $1 AND $2
+		  AND m.longitude BETWEEN $3 AND $4`, args...)
+	if err != nil {
+		return nil, settings, err
+	}
+	defer rows.Close()
+	out := []models.DuplicateCandidate{}
+	for rows.Next() {
+		var c models.DuplicateCandidate
+		var sim sql.NullFloat64
+		if rows.Scan(&c.ID, &c.Text, &c.DomainKey, &c.Status, &c.Latitude, &c.Longitude, &c.SupportCount, &sim) != nil {
+			continue
+		}
+		c.DistanceM = utils.HaversineMeters(lat, lng, c.Latitude, c.Longitude)
+		if c.DistanceM > float64(settings.RadiusM) {
+			continue
+		}
+		if sim.Valid {
+			c.TextSimilarity = sim.Float64
+		} else {
+			c.TextSimilarity = utils.TrigramSimilarity(text, c.Text)
+		}
+		c.SameDomain = domainKey != "" && c.DomainKey == domainKey
+		c.Score = utils.DuplicateScore(c.DistanceM, float64(settings.RadiusM), utils.DomainMatch(domainKey, c.DomainKey), c.TextSimilarity)
+		if c.Score >= settings.Threshold {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].DistanceM < out[j].DistanceM
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, settings, nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"backend/database"
+	"backend/models"
+)
+
+// ClusterCellDegrees — размер ячейки сетки в градусах для зума карты (≈60px на тайле 256px).
+func ClusterCellDegrees(zoom int) float64 {
+	if zoom < 0 {
+		zoom = 0
+	}
+	if zoom > 21 {
+		zoom = 21
+	}
+	return 360.0 / math.Pow(2, float64(zoom)) * 60.0 / 256.0
+}
+
+const clusterAggregates = `
+		COUNT(*)::int,
+		mode() WITHIN GROUP (ORDER BY dk) FILTER (WHERE dk <> ''),
+		COUNT(*) FILTER (WHERE overdue)::int,
+		MIN(id)`
+
+// GetPublicMarkerClusters — сеточная кластеризация публичных меток в bbox.
+// С PostGIS группирует через ST_SnapToGrid, без него — по округлённым lat/lng. Ошибка запроса
+// с PostGIS возвращается как есть: сетка — только для баз без расширения.
+func (r *PostgresMarkerRepository) GetPublicMarkerClusters(swLat, swLng, neLat, neLng float64, zoom int, layer string) ([]models.MarkerCluster, error) {
+	cell := ClusterCellDegrees(zoom)
+	hasBounds := swLat != neLat && swLng != neLng
+	if database.PostGISAvailable() {
+		return queryMarkerClustersPostGIS(swLat, swLng, neLat, neLng, hasBounds, cell, layer)
+	}
+	return queryMarkerClustersGrid(swLat, swLng, neLat, neLng, hasBounds, cell, layer)
+}
+
+func queryMarkerClustersPostGIS(swLat, swLng, neLat, neLng float64, hasBounds bool, cell float64, layer string) ([]models.MarkerCluster, error) {
+	args := []interface{}{cell}
+	bounds := ""
+	if hasBounds {
+		bounds = ` AND ST_Intersects(m.location::geometry, ST_MakeEnvelope($2, $3, $4, $5, 4326))`
+		args = append(args, swLng, swLat, neLng, neLat)
+	}
+	q := fmt.Sprintf(`
+		WITH pts AS (
+			SELECT m.id, m.location::geometry AS geom,
+			       COALESCE(NULLIF(TRIM(m.domain_key), ''), '') AS dk,
+			       %s AS overdue
+			FROM markers m
+			WHERE LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) %s
+			  AND m.location IS NOT NULL%s
+		)
+		SELECT ST_Y(ST_Centroid(ST_Collect(geom))), ST_X(ST_Centroid(ST_Collect(geom))),%s
+		FROM pts
+		GROUP BY ST_SnapToGrid(geom, $1)
+		ORDER BY 3 DESC
+		LIMIT 5000`, overdueSQL("m"), publicMarkersStatusClause(layer), bounds, clusterAggregates)
+	return scanMarkerClusters(q, args...)
+}
+
+func queryMarkerClustersGrid(swLat, swLng, neLat, neLng float64, hasBounds bool, cell float64, layer string) ([]models.MarkerCluster, error) {
+	args := []interface{}{cell}
+	bounds := ""
+	if hasBounds {
+		bounds = ` AND m.latitude BETWEEN $2 AND $3 AND m.longitude BETWEEN $4 AND $5`
+		args = append(args, swLat, neLat, swLng, neLng)
+	}
+	q := fmt.Sprintf(`
+		WITH pts AS (
+			SELECT m.id, m.latitude::float8 AS lat, m.longitude::float8 AS lng,
+			       COALESCE(NULLIF(TRIM(m.domain_key), ''), '') AS dk,
+			       %s AS overdue
+			FROM markers m
+			WHERE LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) %s%s
+		)
+		SELECT AVG(lat)::float8, AVG(lng)::float8,%s
+		FROM pts
+		GROUP BY FLOOR(lat / $1), FLOOR(lng / $1)
+		ORDER BY 3 DESC
+		LIMIT 5000`, overdueSQL("m"), publicMarkersStatusClause(layer), bounds, clusterAggregates)
+	return scanMarkerClusters(q, args...)
+}
+
+func scanMarkerClusters(q string, args ...interface{}) ([]models.MarkerCluster, error) {
+	rows, err := database.DB.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []models.MarkerCluster{}
+	for rows.Next() {
+		var c models.MarkerCluster
+		var dk sql.NullString
+		var minID int
+		if err := rows.Scan(&c.Latitude, &c.Longitude, &c.Count, &dk, &c.OverdueCount, &minID); err != nil {
+			continue
+		}
+		if dk.Valid {
+			c.DomainKey = dk.String
+		}
+		if c.Count == 1 {
+			id := minID
+			c.MarkerID = &id
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
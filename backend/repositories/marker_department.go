@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"backend/database"
+)
+
+var ErrDepartmentNotFound = errors.New("department not found")
+
+func itoaOrEmpty(id int) string {
+	if id <= 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// departmentForDomainSQL — ведомство направления domainExpr (первое по id, как DepartmentIDForDomain).
+func departmentForDomainSQL(domainExpr string) string {
+	return `(SELECT d.id FROM departments d WHERE ` + domainExpr + ` = ANY(d.category_keys) ORDER BY d.id LIMIT 1)`
+}
+
+// reassignedDepartmentSQL — department_id метки m после смены направления на domainExpr: назначенное ведомство
+// переходит к ведомству нового направления. Ещё не назначенное остаётся пустым (его назначит одобрение),
+// метка без нового направления сохраняет прежнее ведомство.
+func reassignedDepartmentSQL(domainExpr string) string {
+	return `CASE WHEN m.department_id IS NULL OR ` + domainExpr + ` IS NULL
+		OR m.domain_key IS NOT DISTINCT FROM ` + domainExpr + ` THEN m.department_id
+		ELSE ` + departmentForDomainSQL(domainExpr) + ` END`
+}
+
+// MarkerDepartmentID — назначенное ведомство метки (0 — не назначено).
+func MarkerDepartmentID(markerID int) (int, error) {
+	var dept sql.NullInt64
+	if err := database.DB.QueryRow(`SELECT department_id FROM markers WHERE id = $1`, markerID).Scan(&dept); err != nil {
+		return 0, err
+	}
+	return int(dept.Int64), nil
+}
+
+// assignDepartmentFromDomain назначает ведомство по domain_key, если оно ещё не выбрано
+// (ручное назначение модератора не перезаписывается). Возвращает итоговое ведомство.
+// Вызывается из транзакции одобрения: ведомство не остаётся, если смена статуса не прошла.
+func assignDepartmentFromDomain(q dbExecutor, markerID int, actorID *int) (int, error) {
+	var current sql.NullInt64
+	var domainKey sql.NullString
+	if err := q.QueryRow(
+		`SELECT department_id, domain_key FROM markers WHERE id = $1`, markerID,
+	).Scan(&current, &domainKey); err != nil {
+		return 0, err
+	}
+	if current.Valid {
+		return int(current.Int64), nil
+	}
+	deptID := DepartmentIDForDomain(strings.TrimSpace(domainKey.String))
+	if deptID <= 0 {
+		return 0, nil
+	}
+	res, err := q.Exec(
+		`UPDATE markers SET department_id = $1 WHERE id = $2 AND department_id IS NULL`, deptID, markerID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if err := insertMarkerChange(q, markerID, "department_id", "", strconv.Itoa(deptID), actorID); err != nil {
+			return 0, err
+		}
+	}
+	return deptID, nil
+}
+
+// ReassignMarkerDepartment — ручное переназначение ведомства с причиной; возвращает прежнее ведомство.
+// Смена ведомства, журнал и пересчёт срока решения — одна транзакция.
+func ReassignMarkerDepartment(markerID, departmentID int, reason string, actorID *int) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var exists bool
+	if err := tx.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM departments WHERE id = $1)`, departmentID,
+	).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrDepartmentNotFound
+	}
+	var old sql.NullInt64
+	if err := tx.QueryRow(
+		`SELECT department_id FROM markers WHERE id = $1 FOR UPDATE`, markerID,
+	).Scan(&old); err != nil {
+		return 0, err
+	}
+	oldID := int(old.Int64)
+	if oldID == departmentID {
+		return oldID, nil
+	}
+	if _, err := tx.Exec(
+		`UPDATE markers SET department_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, departmentID, markerID,
+	); err != nil {
+		return 0, err
+	}
+	if err := insertMarkerChange(tx, markerID, "department_id", itoaOrEmpty(oldID), strconv.Itoa(departmentID), actorID); err != nil {
+		return 0, err
+	}
+	if reason = strings.TrimSpace(reason); reason != "" {
+		if err := insertMarkerChange(tx, markerID, "department_reason", "", reason, actorID); err != nil {
+			return 0, err
+		}
+	}
+	// у нового ведомства может быть свой календарь — пересчитываем срок решения
+	if _, err := recomputeSLADueDatesWith(tx, actorID, []int{markerID}); err != nil {
+		return 0, err
+	}
+	return oldID, tx.Commit()
+}
+
+// UserDepartment — ведомство пользователя и флаг представителя.
+func UserDepartment(userID int) (departmentID int, isRep bool, err error) {
+	var dept sql.NullInt64
+	err = database.DB.QueryRow(
+		`SELECT department_id, COALESCE(is_department_rep, FALSE) FROM users WHERE id = $1`, userID,
+	).Scan(&dept, &isRep)
+	return int(dept.Int64), isRep, err
+}
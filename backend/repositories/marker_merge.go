@@ -0,0 +1,249 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/database"
+)
+
+const maxMergeSources = 50
+
+var (
+	ErrMergeNoSources     = errors.New("Укажите хотя бы одно обращение для слияния")
+	ErrMergeTooMany       = fmt.Errorf("За один раз можно слить не более %d обращений", maxMergeSources)
+	ErrMergeIntoSelf      = errors.New("Нельзя слить обращение само с собой")
+	ErrMergeCanonicalDone = errors.New("Основное обращение уже слито с другим")
+	// ErrMergeCanonicalClosed — дубли нельзя прятать за отклонённым или решённым обращением
+	ErrMergeCanonicalClosed = errors.New("Основное обращение закрыто: слить можно только в открытое (на проверке, одобрено, в работе, переоткрыто)")
+)
+
+// mergeCanonicalStatuses — статусы, в которых обращение может принять дубли.
+var mergeCanonicalStatuses = map[string]bool{
+	"pending": true, "approved": true, "in_progress": true, "reopened": true,
+}
+
+// MergeSourceError — одно из сливаемых обращений не найдено или уже слито.
+type MergeSourceError struct {
+	MarkerID int
+	Reason   string
+}
+
+func (e *MergeSourceError) Error() string {
+	return fmt.Sprintf("Обращение #%d: %s", e.MarkerID, e.Reason)
+}
+
+// MergedMarker — поглощённое обращение и его автор (для уведомлений).
+type MergedMarker struct {
+	ID        int    `json:"id"`
+	OwnerID   int    `json:"owner_id,omitempty"`
+	OldStatus string `json:"old_status"`
+	Text      string `json:"text"`
+}
+
+// MergeResult — что перенесено на основное обращение.
+type MergeResult struct {
+	CanonicalID      int            `json:"canonical_id"`
+	Merged           []MergedMarker `json:"merged"`
+	SupportsMoved    int            `json:"supports_moved"`
+	CommentsMoved    int            `json:"comments_moved"`
+	ReviewsMoved     int            `json:"reviews_moved"`
+	FavoritesMoved   int            `json:"favorites_moved"`
+	GeoNotifications int            `json:"geo_notifications_moved"`
+}
+
+// MergeMarkers — сливает дубли в основное обращение одной транзакцией.
+// Поддержки, отзывы и избранное переносятся с учётом UNIQUE (у пользователя остаётся одна запись),
+// автор дубля становится сторонником основного обращения. Дубли получают статус merged и merged_into.
+func MergeMarkers(canonicalID int, sourceIDs []int, note string, actorID *int) (*MergeResult, error) {
+	sources := normalizeMergeSources(sourceIDs)
+	if len(sources) == 0 {
+		return nil, ErrMergeNoSources
+	}
+	if len(sources) > maxMergeSources {
+		return nil, ErrMergeTooMany
+	}
+	for _, id := range sources {
+		if id == canonicalID {
+			return nil, ErrMergeIntoSelf
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// блокируем в порядке id, чтобы встречные слияния не взаимоблокировались
+	all := append([]int{canonicalID}, sources...)
+	sort.Ints(all)
+	type lockedMarker struct {
+		owner    int
+		status   string
+		text     string
+		mergedTo sql.NullInt64
+	}
+	locked := map[int]lockedMarker{}
+	rows, err := tx.Query(`
+		SELECT id, COALESCE(user_id, 0), LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending')),
+		       COALESCE(text, ''), merged_into
+		FROM markers WHERE id = ANY($1) ORDER BY id FOR UPDATE`, int64Array(all))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		var m lockedMarker
+		if err := rows.Scan(&id, &m.owner, &m.status, &m.text, &m.mergedTo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		locked[id] = m
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	canonical, ok := locked[canonicalID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if canonical.status == "merged" || canonical.mergedTo.Valid {
+		return nil, ErrMergeCanonicalDone
+	}
+	if !mergeCanonicalStatuses[canonical.status] {
+		return nil, ErrMergeCanonicalClosed
+	}
+	res := &MergeResult{CanonicalID: canonicalID}
+	for _, id := range sources {
+		m, ok := locked[id]
+		if !ok {
+			return nil, &MergeSourceError{MarkerID: id, Reason: "не найдено"}
+		}
+		if m.status == "merged" || m.mergedTo.Valid {
+			return nil, &MergeSourceError{MarkerID: id, Reason: "уже слито с другим обращением"}
+		}
+		res.Merged = append(res.Merged, MergedMarker{ID: id, OwnerID: m.owner, OldStatus: m.status, Text: m.text})
+	}
+	src := int64Array(sources)
+
+	// поддержки: существующие + авторы дублей; автор основного обращения сам себя не поддерживает
+	r, err := tx.Exec(`
+		INSERT INTO marker_supports (marker_id, user_id, created_at)
+		SELECT $1, s.user_id, MIN(s.created_at) FROM (
+			SELECT user_id, created_at FROM marker_supports WHERE marker_id = ANY($2)
+			UNION ALL
+			SELECT user_id, created_at FROM markers WHERE id = ANY($2) AND user_id IS NOT NULL
+		) s
+		WHERE s.user_id IS DISTINCT FROM (SELECT user_id FROM markers WHERE id = $1)
+		GROUP BY s.user_id
+		ON CONFLICT (marker_id, user_id) DO NOTHING`, canonicalID, src)
+	if err != nil {
+		return nil, err
+	}
+	res.SupportsMoved = rowsAffected(r)
+	if _, err := tx.Exec(`DELETE FROM marker_supports WHERE marker_id = ANY($1)`, src); err != nil {
+		return nil, err
+	}
+
+	r, err = tx.Exec(`UPDATE comments SET marker_id = $1 WHERE marker_id = ANY($2)`, canonicalID, src)
+	if err != nil {
+		return nil, err
+	}
+	res.CommentsMoved = rowsAffected(r)
+
+	// отзывы: от пользователя остаётся один — уже оставленный на основном, иначе самый свежий из дублей
+	r, err = tx.Exec(`
+		INSERT INTO marker_reviews (marker_id, user_id, rating, comment, created_at, updated_at)
+		SELECT DISTINCT ON (user_id) $1, user_id, rating, comment, created_at, updated_at
+		FROM marker_reviews WHERE marker_id = ANY($2)
+		ORDER BY user_id, updated_at DESC NULLS LAST, id DESC
+		ON CONFLICT (marker_id, user_id) DO NOTHING`, canonicalID, src)
+	if err != nil {
+		return nil, err
+	}
+	res.ReviewsMoved = rowsAffected(r)
+	if _, err := tx.Exec(`DELETE FROM marker_reviews WHERE marker_id = ANY($1)`, src); err != nil {
+		return nil, err
+	}
+
+	r, err = tx.Exec(`
+		INSERT INTO marker_favorites (user_id, marker_id, created_at)
+		SELECT user_id, $1, MIN(created_at) FROM marker_favorites WHERE marker_id = ANY($2)
+		GROUP BY user_id
+		ON CONFLICT (user_id, marker_id) DO NOTHING`, canonicalID, src)
+	if err != nil {
+		return nil, err
+	}
+	res.FavoritesMoved = rowsAffected(r)
+	if _, err := tx.Exec(`DELETE FROM marker_favorites WHERE marker_id = ANY($1)`, src); err != nil {
+		return nil, err
+	}
+
+	r, err = tx.Exec(`
+		UPDATE notifications SET marker_id = $1
+		WHERE marker_id = ANY($2) AND notif_type LIKE 'geo\_%'`, canonicalID, src)
+	if err != nil {
+		return nil, err
+	}
+	res.GeoNotifications = rowsAffected(r)
+
+	// ранее слитые в дубль обращения теперь указывают на основное
+	if _, err := tx.Exec(`UPDATE markers SET merged_into = $1 WHERE merged_into = ANY($2)`, canonicalID, src); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE markers SET status = 'merged', merged_into = $1, merged_at = CURRENT_TIMESTAMP,
+		                   updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($2)`, canonicalID, src); err != nil {
+		return nil, err
+	}
+
+	canonicalStr := strconv.Itoa(canonicalID)
+	var notePtr *string
+	if note = strings.TrimSpace(note); note != "" {
+		notePtr = &note
+	}
+	for _, m := range res.Merged {
+		if err := insertMarkerStatusLog(tx, m.ID, m.OldStatus, "merged", actorID, notePtr); err != nil {
+			return nil, err
+		}
+		if err := insertMarkerChange(tx, m.ID, "merged_into", "", canonicalStr, actorID); err != nil {
+			return nil, err
+		}
+		if err := insertMarkerChange(tx, canonicalID, "merged_from", "", strconv.Itoa(m.ID), actorID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE markers SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, canonicalID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func normalizeMergeSources(ids []int) []int {
+	seen := map[int]bool{}
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+func rowsAffected(r sql.Result) int {
+	n, _ := r.RowsAffected()
+	return int(n)
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"backend/database"
+)
+
+// ReopenWindowDays — сколько дней после решения его можно оспорить.
+const ReopenWindowDays = 14
+
+// ReopenSupportersThreshold — сколько поддержавших должны оспорить решение, если это не владелец.
+const ReopenSupportersThreshold = 3
+
+var ErrReopenAlreadyRequested = errors.New("reopen already requested")
+
+type ReopenRequest struct {
+	ID       int `json:"id"`
+	MarkerID int `json:"marker_id"`
+	UserID   int `json:"user_id"`
+	// UserName — отображаемое имя; список публичный, поэтому email не отдаётся
+	UserName  string     `json:"user_name,omitempty"`
+	IsOwner   bool       `json:"is_owner"`
+	Reason    string     `json:"reason"`
+	ImageURL  string     `json:"image_url"`
+	Outcome   string     `json:"outcome"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// ReopenWindowEnd — до какого момента решение можно оспорить.
+func ReopenWindowEnd(resolvedAt time.Time) time.Time {
+	return resolvedAt.AddDate(0, 0, ReopenWindowDays)
+}
+
+// ExpireStaleReopenRequests закрывает заявки, поданные до текущего решения (прошлый цикл).
+func ExpireStaleReopenRequests(markerID int, resolvedAt time.Time) error {
+	_, err := database.DB.Exec(`
+		UPDATE marker_reopen_requests SET outcome = 'expired', closed_at = NOW()
+		WHERE marker_id = $1 AND outcome = 'pending' AND created_at < $2`,
+		markerID, resolvedAt)
+	return err
+}
+
+func CreateReopenRequest(markerID, userID int, isOwner bool, reason, imageURL string) (int, error) {
+	var id int
+	err := database.DB.QueryRow(`
+		INSERT INTO marker_reopen_requests (marker_id, user_id, is_owner, reason, image_url)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+		RETURNING id`,
+		markerID, userID, isOwner, strings.TrimSpace(reason), strings.TrimSpace(imageURL),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrReopenAlreadyRequested
+	}
+	return id, err
+}
+
+// CountPendingSupporterReopenRequests — сколько поддержавших (не владелец) оспорили текущее решение.
+func CountPendingSupporterReopenRequests(markerID int) (int, error) {
+	var n int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*)::int FROM marker_reopen_requests
+		WHERE marker_id = $1 AND outcome = 'pending' AND NOT is_owner`, markerID,
+	).Scan(&n)
+	return n, err
+}
+
+// CloseReopenRequests проставляет итог всем ожидающим заявкам метки.
+func CloseReopenRequests(markerID int, outcome string) error {
+	_, err := database.DB.Exec(`
+		UPDATE marker_reopen_requests SET outcome = $2, closed_at = NOW()
+		WHERE marker_id = $1 AND outcome = 'pending'`, markerID, outcome)
+	return err
+}
+
+// FailReopenRequest закрывает заявку, по которой переоткрыть обращение не удалось: иначе ожидающая
+// заявка блокировала бы повторную попытку того же пользователя.
+func FailReopenRequest(requestID int) error {
+	_, err := database.DB.Exec(`
+		UPDATE marker_reopen_requests SET outcome = 'failed', closed_at = NOW()
+		WHERE id = $1 AND outcome = 'pending'`, requestID)
+	return err
+}
+
+func ListPendingReopenRequests(markerID int) ([]ReopenRequest, error) {
+	rows, err := database.DB.Query(`
+		SELECT q.id, q.marker_id, q.user_id, COALESCE(NULLIF(TRIM(u.display_name), ''), ''), q.is_owner, q.reason, q.image_url,
+		       q.outcome, q.created_at, q.closed_at
+		FROM marker_reopen_requests q
+		LEFT JOIN users u ON u.id = q.user_id
+		WHERE q.marker_id = $1 AND q.outcome = 'pending'
+		ORDER BY q.created_at ASC`, markerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []ReopenRequest
+	for rows.Next() {
+		var q ReopenRequest
+		var closedAt sql.NullTime
+		if err := rows.Scan(&q.ID, &q.MarkerID, &q.UserID, &q.UserName, &q.IsOwner, &q.Reason, &q.ImageURL,
+			&q.Outcome, &q.CreatedAt, &closedAt); err != nil {
+			continue
+		}
+		if closedAt.Valid {
+			t := closedAt.Time
+			q.ClosedAt = &t
+		}
+		list = append(list, q)
+	}
+	return list, nil
+}
+
+// ResetMarkerAfterPhoto снимает оспоренное фото «после»; возвращает прежнее значение.
+func ResetMarkerAfterPhoto(markerID int) (string, error) {
+	var old sql.NullString
+	err := database.DB.QueryRow(`
+		UPDATE markers m SET image_after_url = NULL
+		FROM (SELECT id, image_after_url FROM markers WHERE id = $1 FOR UPDATE) prev
+		WHERE m.id = prev.id
+		RETURNING prev.image_after_url`, markerID,
+	).Scan(&old)
+	if err != nil {
+		return "", err
+	}
+	return old.String, nil
+}
@@ -4,6 +4,7 @@ import (
 	"backend/database"
 	"backend/models"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -19,14 +20,26 @@ type MarkerRepository interface {
 	GetMarkerNotifyMeta(markerID int) (ownerID int, status string, text string, err error)
 	GetByID(id int) (*models.Marker, error)
 	GetPublicMarkersInBounds(swLat, swLng, neLat, neLng float64, layer string) ([]models.Marker, error)
+	GetPublicMarkerClusters(swLat, swLng, neLat, neLng float64, zoom int, layer string) ([]models.MarkerCluster, error)
+	GetPublicMarkerTile(z, x, y int, layer string) ([]byte, error)
 	Create(req models.CreateMarkerRequest) (int, error)
 	Delete(id int) error
-	UpdateStatus(id int, status string, moderatorNote *string) error
+	UpdateStatus(id int, change MarkerStatusChange) error
 	GetUserEmail(userID int) (string, error)
 	UserExists(id int) bool
 	GetMarkerOwnerUserID(markerID int) (int, error)
+	GetImageAfterURL(markerID int) (string, error)
 }
 
+// ErrMarkerStatusChanged — статус метки сменили параллельно, переход проверялся по устаревшему значению.
+var ErrMarkerStatusChanged = errors.New("marker status changed concurrently")
+
+// ErrMarkerHasMergedDuplicates — в метку слиты дубли; удаление оставило бы их со статусом merged без ссылки.
+var ErrMarkerHasMergedDuplicates = errors.New("marker has merged duplicates")
+
+// markerStatusExpr — статус метки в том виде, в каком его сравнивает схема переходов.
+const markerStatusExpr = `LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending'))`
+
 type PostgresMarkerRepository struct{}
 
 func NewMarkerRepository() MarkerRepository {
@@ -38,7 +51,8 @@ const markerSelectBase = `
 		       m.image_url, COALESCE(m.image_after_url, ''), COALESCE(m.address_text, ''),
 		       m.domain_key, m.group_key, m.issue_key, m.ai_confidence,
 		       m.status, m.moderator_note, m.response_due_at, m.resolution_due_at, m.resolved_at,
-		       m.created_at, m.updated_at,
+		       m.sla_paused_at, m.sla_paused_seconds, m.department_id, COALESCE(m.ai_domain_key, ''),
+		       m.merged_into, m.created_at, m.updated_at,
 		       COALESCE(NULLIF(TRIM(u.display_name), ''), u.email) AS user_email,
 		       (SELECT COUNT(*)::int FROM marker_reviews r WHERE r.marker_id = m.id),
 		       (SELECT AVG(r.rating)::float8 FROM marker_reviews r WHERE r.marker_id = m.id),
@@ -50,15 +64,16 @@ func scanMarkerFromRows(rows *sql.Rows) (models.Marker, error) {
 	var m models.Marker
 	var img, imgAfter, addr, email, dkey, gkey, ikey, modNote sql.NullString
 	var ai sql.NullFloat64
+	var deptID, mergedInto sql.NullInt64
 	var reviewCnt sql.NullInt64
 	var reviewAvg sql.NullFloat64
 	var supportCnt sql.NullInt64
-	var respDue, resDue, resolvedAt sql.NullTime
+	var respDue, resDue, resolvedAt, pausedAt sql.NullTime
 
 	if err := rows.Scan(&m.ID, &m.UserID, &m.Text, &m.Latitude, &m.Longitude,
 		&img, &imgAfter, &addr, &dkey, &gkey, &ikey, &ai, &m.Status, &modNote,
-		&respDue, &resDue, &resolvedAt,
-		&m.CreatedAt, &m.UpdatedAt, &email,
+		&respDue, &resDue, &resolvedAt, &pausedAt, &m.SLAPausedSeconds, &deptID, &m.AIDomainKey,
+		&mergedInto, &m.CreatedAt, &m.UpdatedAt, &email,
 		&reviewCnt, &reviewAvg, &supportCnt); err != nil {
 		return m, err
 	}
@@ -74,6 +89,18 @@ func scanMarkerFromRows(rows *sql.Rows) (models.Marker, error) {
 		t := resolvedAt.Time
 		m.ResolvedAt = &t
 	}
+	if pausedAt.Valid {
+		t := pausedAt.Time
+		m.SLAPausedAt = &t
+	}
+	if deptID.Valid {
+		v := int(deptID.Int64)
+		m.DepartmentID = &v
+	}
+	if mergedInto.Valid {
+		v := int(mergedInto.Int64)
+		m.MergedInto = &v
+	}
 	if img.Valid {
 		m.ImageURL = img.String
 	}
@@ -116,16 +143,25 @@ func scanMarkerFromRows(rows *sql.Rows) (models.Marker, error) {
 	return m, nil
 }
 
+// IsPublicMarkerStatus — метка в этом статусе видна на публичной карте (слой «all»).
+func IsPublicMarkerStatus(status string) bool {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "approved", "in_progress", "reopened", "resolved":
+		return true
+	}
+	return false
+}
+
 func publicMarkersStatusClause(layer string) string {
 	switch strings.ToLower(strings.TrimSpace(layer)) {
 	case "resolved":
 		return `IN ('resolved')`
 	case "all":
-		return `IN ('approved', 'in_progress', 'resolved')`
+		return `IN ('approved', 'in_progress', 'reopened', 'resolved')`
 	case "active", "":
-		return `IN ('approved', 'in_progress')`
+		return `IN ('approved', 'in_progress', 'reopened')`
 	default:
-		return `IN ('approved', 'in_progress')`
+		return `IN ('approved', 'in_progress', 'reopened')`
 	}
 }
 
@@ -216,6 +252,27 @@ func (r *PostgresMarkerRepository) ListByUserID(userID int) ([]models.Marker, er
 	return markers, nil
 }
 
+// MarkerRouteInfo — признаки метки для маршрутизации realtime-событий.
+type MarkerRouteInfo struct {
+	Lat, Lng  float64
+	DomainKey string
+	Status    string
+	OwnerID   int
+}
+
+// GetMarkerRouteInfo — координаты, направление, статус и автор метки для маршрутизации realtime-событий.
+func GetMarkerRouteInfo(markerID int) (MarkerRouteInfo, error) {
+	var info MarkerRouteInfo
+	var dk sql.NullString
+	var owner sql.NullInt64
+	err := database.DB.QueryRow(
+		`SELECT latitude, longitude, domain_key, `+markerStatusExpr+`, user_id FROM markers WHERE id = $1`, markerID,
+	).Scan(&info.Lat, &info.Lng, &dk, &info.Status, &owner)
+	info.DomainKey = dk.String
+	info.OwnerID = int(owner.Int64)
+	return info, err
+}
+
 func (r *PostgresMarkerRepository) GetMarkerNotifyMeta(markerID int) (ownerID int, status string, text string, err error) {
 	var uid sql.NullInt64
 	var st, txt sql.NullString
@@ -264,8 +321,8 @@ func buildMarkerFilterSQL(domainKey, status string, overdueOnly bool) (clause st
 			(LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) = 'pending'
 			 AND m.response_due_at IS NOT NULL AND m.response_due_at < NOW())
 			OR
-			(LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress')
-			 AND m.resolution_due_at IS NOT NULL AND m.resolution_due_at < NOW())
+			(LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened')
+			 AND m.resolution_due_at IS NOT NULL AND m.resolution_due_at < NOW() AND m.sla_paused_at IS NULL)
 		)`)
 	}
 	if len(parts) == 0 {
@@ -412,8 +469,8 @@ func (r *PostgresMarkerRepository) ModerationDashboard() (*models.ModerationDash
 			(LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) = 'pending'
 			 AND m.response_due_at IS NOT NULL AND m.response_due_at < NOW())
 			OR
-			(LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress')
-			 AND m.resolution_due_at IS NOT NULL AND m.resolution_due_at < NOW())
+			(LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened')
+			 AND m.resolution_due_at IS NOT NULL AND m.resolution_due_at < NOW() AND m.sla_paused_at IS NULL)
 	`).Scan(&dash.OverdueCount)
 
 	return dash, nil
@@ -435,68 +492,156 @@ func (r *PostgresMarkerRepository) Create(req models.CreateMarkerRequest) (int,
 	if req.AIConfidence != nil {
 		ai = sql.NullFloat64{Float64: *req.AIConfidence, Valid: true}
 	}
-	respDue := ComputeResponseDue(time.Now())
+	now := time.Now()
+	respDue := ComputeResponseDue(now)
 	addr := strings.TrimSpace(req.AddressText)
 	err := database.DB.QueryRow(`
-		INSERT INTO markers (user_id,text,latitude,longitude,address_text,image_url,domain_key,group_key,issue_key,ai_confidence,status,response_due_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,'pending',$11) RETURNING id`,
+		INSERT INTO markers (user_id,text,latitude,longitude,address_text,image_url,domain_key,group_key,issue_key,ai_confidence,status,response_due_at,response_sla_from,
+		                     ai_domain_key,ai_group_key,ai_issue_key,ai_source)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,'pending',$11,$12,$13,$14,$15,$16) RETURNING id`,
 		req.UserID, req.Text, req.Latitude, req.Longitude, addr, req.ImageURL,
-		dkey, gkey, ikey, ai, respDue,
+		dkey, gkey, ikey, ai, respDue, now,
+		nullStr(req.AIDomainKey), nullStr(req.AIGroupKey), nullStr(req.AIIssueKey), nullStr(req.AISource),
 	).Scan(&id)
 	return id, err
 }
 
+// Delete — удаление метки; основное обращение со слитыми дублями не удаляется (ErrMarkerHasMergedDuplicates).
 func (r *PostgresMarkerRepository) Delete(id int) error {
+	var hasDuplicates bool
+	if err := database.DB.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM markers WHERE merged_into = $1)`, id,
+	).Scan(&hasDuplicates); err != nil {
+		return err
+	}
+	if hasDuplicates {
+		return ErrMarkerHasMergedDuplicates
+	}
 	_, err := database.DB.Exec("DELETE FROM markers WHERE id=$1", id)
 	return err
 }
 
-func (r *PostgresMarkerRepository) UpdateStatus(id int, status string, moderatorNote *string) error {
+// MarkerStatusChange — смена статуса вместе с тем, что пишется с ней в одной транзакции.
+type MarkerStatusChange struct {
+	FromStatus    string
+	Status        string
+	ModeratorNote *string
+	// ImageAfterURL — фото «после» при решении (пусто — не меняется)
+	ImageAfterURL string
+	ActorID       *int
+}
+
+// UpdateStatus — смена статуса, только если метка всё ещё в FromStatus (иначе ErrMarkerStatusChanged):
+// переход проверяется по прочитанному раньше статусу, и параллельный запрос мог его уже сменить.
+// Ведомство при одобрении, сроки, фото «после», журнал статусов и изменений пишутся в той же транзакции.
+func (r *PostgresMarkerRepository) UpdateStatus(id int, change MarkerStatusChange) error {
+	fromStatus, status, moderatorNote := change.FromStatus, change.Status, change.ModeratorNote
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 	var domainKey sql.NullString
-	err := database.DB.QueryRow(
-		`SELECT domain_key FROM markers WHERE id = $1`, id,
-	).Scan(&domainKey)
+	var deptID sql.NullInt64
+	var cur string
+	err = tx.QueryRow(
+		`SELECT domain_key, department_id, `+markerStatusExpr+` FROM markers WHERE id = $1 FOR UPDATE`, id,
+	).Scan(&domainKey, &deptID, &cur)
 	if err != nil {
 		return err
 	}
+	if cur != fromStatus {
+		return ErrMarkerStatusChanged
+	}
 	dk := ""
 	if domainKey.Valid {
 		dk = strings.TrimSpace(domainKey.String)
 	}
+	// ведомство назначается до расчёта срока решения: у него может быть свой календарь
+	if status == "approved" && !deptID.Valid {
+		dept, err := assignDepartmentFromDomain(tx, id, change.ActorID)
+		if err != nil {
+			return err
+		}
+		if dept > 0 {
+			deptID = sql.NullInt64{Int64: int64(dept), Valid: true}
+		}
+	}
+	// пауза закрывается при любой смене статуса: срок решения сдвигается на её длительность
+	if _, err := resumeMarkerSLAWith(tx, id, nil); err != nil {
+		return err
+	}
 	now := time.Now()
 
-	var respDue, resDue, resolvedAt interface{}
-	respDue = nil
-	resDue = nil
-	resolvedAt = nil
+	var respDue, resDue, resolvedAt, respFrom, resFrom interface{}
 
 	switch status {
 	case "pending":
 		rd := ComputeResponseDue(now)
 		respDue = rd
-	case "approved", "in_progress":
-		rd := ComputeResolutionDue(now, dk)
+		respFrom = now
+	case "approved", "in_progress", "reopened":
+		var rd time.Time
+		if deptID.Valid {
+			rd = ComputeResolutionDueForDepartment(now, dk, int(deptID.Int64))
+		} else {
+			rd = ComputeResolutionDue(now, dk)
+		}
 		resDue = rd
+		resFrom = now
 	case "resolved":
 		resolvedAt = now
 	}
 
+	var res sql.Result
 	if moderatorNote != nil {
-		_, err = database.DB.Exec(
+		res, err = tx.Exec(
 			`UPDATE markers SET status = $1, updated_at = CURRENT_TIMESTAMP,
-			 response_due_at = $3, resolution_due_at = $4, resolved_at = $5, moderator_note = $6
-			 WHERE id = $2`,
-			status, id, respDue, resDue, resolvedAt, *moderatorNote,
+			 response_due_at = $3,
+			 resolution_due_at = CASE WHEN $1 = 'resolved' THEN resolution_due_at ELSE $4 END,
+			 resolved_at = $5, moderator_note = $6,
+			 response_sla_from = $7, resolution_sla_from = $8,
+			 sla_paused_seconds = CASE WHEN $8::timestamp IS NULL THEN sla_paused_seconds ELSE 0 END
+			 WHERE id = $2 AND `+markerStatusExpr+` = $9`,
+			status, id, respDue, resDue, resolvedAt, *moderatorNote, respFrom, resFrom, fromStatus,
 		)
+	} else {
+		res, err = tx.Exec(
+			`UPDATE markers SET status = $1, updated_at = CURRENT_TIMESTAMP,
+			 response_due_at = $3,
+			 resolution_due_at = CASE WHEN $1 = 'resolved' THEN resolution_due_at ELSE $4 END,
+			 resolved_at = $5,
+			 response_sla_from = $6, resolution_sla_from = $7,
+			 sla_paused_seconds = CASE WHEN $7::timestamp IS NULL THEN sla_paused_seconds ELSE 0 END
+			 WHERE id = $2 AND `+markerStatusExpr+` = $8`,
+			status, id, respDue, resDue, resolvedAt, respFrom, resFrom, fromStatus,
+		)
+	}
+	if err != nil {
 		return err
 	}
-	_, err = database.DB.Exec(
-		`UPDATE markers SET status = $1, updated_at = CURRENT_TIMESTAMP,
-		 response_due_at = $3, resolution_due_at = $4, resolved_at = $5
-		 WHERE id = $2`,
-		status, id, respDue, resDue, resolvedAt,
-	)
-	return err
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrMarkerStatusChanged
+	}
+	if change.ImageAfterURL != "" {
+		if _, err := tx.Exec(
+			`UPDATE markers SET image_after_url = $1 WHERE id = $2`, change.ImageAfterURL, id,
+		); err != nil {
+			return err
+		}
+	}
+	if err := insertMarkerStatusLog(tx, id, fromStatus, status, change.ActorID, moderatorNote); err != nil {
+		return err
+	}
+	if err := insertMarkerChange(tx, id, "status", fromStatus, status, change.ActorID); err != nil {
+		return err
+	}
+	if change.ImageAfterURL != "" {
+		if err := insertMarkerChange(tx, id, "image_after_url", "", change.ImageAfterURL, change.ActorID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *PostgresMarkerRepository) UpdateMarkerMeta(id, userID int, imageAfterURL, addressText string) error {
@@ -511,6 +656,7 @@ func (r *PostgresMarkerRepository) UpdateMarkerMeta(id, userID int, imageAfterUR
 		WHERE id = $3 AND (
 			user_id = $4
 			OR EXISTS (SELECT 1 FROM users WHERE id = $4 AND (is_moderator OR is_admin))
+			OR EXISTS (SELECT 1 FROM users WHERE id = $4 AND is_department_rep AND department_id = markers.department_id)
 		)`,
 		imageAfterURL, addressText, id, userID)
 	if err != nil {
@@ -562,3 +708,11 @@ func (r *PostgresMarkerRepository) GetMarkerOwnerUserID(markerID int) (int, erro
 	}
 	return int(uid.Int64), nil
 }
+
+func (r *PostgresMarkerRepository) GetImageAfterURL(markerID int) (string, error) {
+	var url string
+	err := database.DB.QueryRow(
+		`SELECT COALESCE(TRIM(image_after_url), '') FROM markers WHERE id = $1`, markerID,
+	).Scan(&url)
+	return url, err
+}
@@ -30,7 +30,15 @@ func ResolutionDaysForDomain(domainKey string) int {
 		`SELECT resolution_days FROM classification_domains WHERE domain_key = $1`,
 		domainKey,
 	).Scan(&days)
-	if err != nil || !days.Valid || days.Int64 < 1 {
+	if err != nil {
+		days = sql.NullInt64{}
+	}
+	return resolutionDays(domainKey, days)
+}
+
+// resolutionDays — срок из classification_domains.resolution_days, иначе значение по умолчанию для направления.
+func resolutionDays(domainKey string, days sql.NullInt64) int {
+	if !days.Valid || days.Int64 < 1 {
 		if d, ok := seedResolutionDays[domainKey]; ok {
 			return d
 		}
@@ -53,15 +61,26 @@ func EnrichMarkerSLA(m *models.Marker) {
 		m.IsOverdue = true
 		return
 	}
-	if (st == "approved" || st == "in_progress") && m.ResolutionDueAt != nil && now.After(*m.ResolutionDueAt) {
+	// на паузе (ждём ответа заявителя) срок не истекает
+	if m.SLAPausedAt != nil {
+		return
+	}
+	if (st == "approved" || st == "in_progress" || st == "reopened") && m.ResolutionDueAt != nil && now.After(*m.ResolutionDueAt) {
 		m.IsOverdue = true
 	}
 }
 
+// ComputeResolutionDue — срок решения в рабочих днях по календарю ведомства, отвечающего за направление.
 func ComputeResolutionDue(from time.Time, domainKey string) time.Time {
-	return from.AddDate(0, 0, ResolutionDaysForDomain(domainKey))
+	return ComputeResolutionDueForDepartment(from, domainKey, DepartmentIDForDomain(strings.TrimSpace(domainKey)))
+}
+
+// ComputeResolutionDueForDepartment — то же по календарю назначенного ведомства (departmentID <= 0 — общий).
+func ComputeResolutionDueForDepartment(from time.Time, domainKey string, departmentID int) time.Time {
+	return LoadWorkCalendar(departmentID).AddWorkdays(from, ResolutionDaysForDomain(domainKey))
 }
 
+// ComputeResponseDue — срок первичной реакции модерации в рабочих днях по общему календарю.
 func ComputeResponseDue(from time.Time) time.Time {
-	return from.AddDate(0, 0, DefaultResponseDays)
+	return LoadWorkCalendar(0).AddWorkdays(from, DefaultResponseDays)
 }
@@ -18,6 +18,10 @@ type MarkerStatusLogEntry struct {
 }
 
 func InsertMarkerStatusLog(markerID int, oldStatus, newStatus string, actorUserID *int, note *string) error {
+	return insertMarkerStatusLog(database.DB, markerID, oldStatus, newStatus, actorUserID, note)
+}
+
+func insertMarkerStatusLog(q dbExecutor, markerID int, oldStatus, newStatus string, actorUserID *int, note *string) error {
 	var noteVal sql.NullString
 	if note != nil && *note != "" {
 		noteVal = sql.NullString{String: *note, Valid: true}
@@ -26,7 +30,7 @@ func InsertMarkerStatusLog(markerID int, oldStatus, newStatus string, actorUserI
 	if actorUserID != nil && *actorUserID > 0 {
 		actor = sql.NullInt64{Int64: int64(*actorUserID), Valid: true}
 	}
-	_, err := database.DB.Exec(`
+	_, err := q.Exec(`
 		INSERT INTO marker_status_log (marker_id, old_status, new_status, actor_user_id, moderator_note)
 		VALUES ($1, $2, $3, $4, $5)`,
 		markerID, nullIfEmpty(oldStatus), newStatus, actor, noteVal,
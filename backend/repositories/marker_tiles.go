@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/database"
+)
+
+// ErrPostGISUnavailable — операция требует расширения postgis.
+var ErrPostGISUnavailable = errors.New("postgis unavailable")
+
+// MarkerTileLayer — имя слоя внутри MVT-тайла.
+const MarkerTileLayer = "markers"
+
+// GetPublicMarkerTile — векторный тайл (Mapbox Vector Tile) публичных меток z/x/y.
+// Атрибуты фич: id, status, domain_key, support_count, is_overdue. Пустой тайл — пустой срез.
+func (r *PostgresMarkerRepository) GetPublicMarkerTile(z, x, y int, layer string) ([]byte, error) {
+	if !database.PostGISAvailable() {
+		return nil, ErrPostGISUnavailable
+	}
+	q := fmt.Sprintf(`
+		WITH bounds AS (
+			SELECT ST_TileEnvelope($1, $2, $3) AS geom
+		),
+		mvtgeom AS (
+			SELECT ST_AsMVTGeom(ST_Transform(m.location::geometry, 3857), bounds.geom) AS geom,
+			       m.id,
+			       LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) AS status,
+			       COALESCE(m.domain_key, '') AS domain_key,
+			       (SELECT COUNT(*)::int FROM marker_supports s WHERE s.marker_id = m.id) AS support_count,
+			       %s AS is_overdue
+			FROM markers m, bounds
+			WHERE LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) %s
+			  AND m.location IS NOT NULL
+			  AND m.location::geometry && ST_Transform(bounds.geom, 4326)
+		)
+		SELECT ST_AsMVT(mvtgeom.*, $4, 4096, 'geom') FROM mvtgeom`,
+		overdueSQL("m"), publicMarkersStatusClause(layer))
+	var tile []byte
+	if err := database.DB.QueryRow(q, z, x, y, MarkerTileLayer).Scan(&tile); err != nil {
+		return nil, err
+	}
+	return tile, nil
+}
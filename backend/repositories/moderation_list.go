@@ -14,6 +14,7 @@ type ModerationListQuery struct {
 	PageSize     int
 	Status       string
 	DomainKey    string
+	DepartmentID int
 	Overdue      bool
 	HasPhoto     bool
 	MinSupports  int
@@ -35,8 +36,8 @@ func overdueSQL(alias string) string {
 		(LOWER(COALESCE(NULLIF(TRIM(%[1]sstatus), ''), 'pending')) = 'pending'
 		 AND %[1]sresponse_due_at IS NOT NULL AND %[1]sresponse_due_at < NOW())
 		OR
-		(LOWER(COALESCE(NULLIF(TRIM(%[1]sstatus), ''), 'pending')) IN ('approved', 'in_progress')
-		 AND %[1]sresolution_due_at IS NOT NULL AND %[1]sresolution_due_at < NOW())
+		(LOWER(COALESCE(NULLIF(TRIM(%[1]sstatus), ''), 'pending')) IN ('approved', 'in_progress', 'reopened')
+		 AND %[1]sresolution_due_at IS NOT NULL AND %[1]sresolution_due_at < NOW() AND %[1]ssla_paused_at IS NULL)
 	)`, a)
 }
 
@@ -54,6 +55,12 @@ func buildModerationWhere(q ModerationListQuery) (clause string, args []interfac
 		}
 	}
 
+	if q.DepartmentID > 0 {
+		parts = append(parts, fmt.Sprintf("m.department_id = $%d", n))
+		args = append(args, q.DepartmentID)
+		n++
+	}
+
 	if q.Status != "" && q.Status != "all" && q.Status != "overdue" {
 		parts = append(parts, fmt.Sprintf("LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) = $%d", n))
 		args = append(args, strings.ToLower(strings.TrimSpace(q.Status)))
@@ -65,7 +72,7 @@ func buildModerationWhere(q ModerationListQuery) (clause string, args []interfac
 	}
 
 	if q.Unresolved {
-		parts = append(parts, `LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('pending', 'approved', 'in_progress')`)
+		parts = append(parts, `LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('pending', 'approved', 'in_progress', 'reopened')`)
 	}
 
 	if q.HasPhoto {
@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/lib/pq"
+)
+
+// Типы ответа ведомства; ответы заявителя всегда OfficialResponseCitizenReply.
+const (
+	OfficialResponseAccepted     = "accepted"
+	OfficialResponseInProgress   = "in_progress"
+	OfficialResponseCompleted    = "completed"
+	OfficialResponseRejected     = "rejected"
+	OfficialResponseCitizenReply = "citizen_reply"
+)
+
+const MaxOfficialResponseAttachments = 5
+
+var ErrInvalidResponseType = errors.New("invalid response_type")
+var ErrInvalidAttachment = errors.New("attachments must be uploaded files")
+
+func ValidOfficialResponseType(t string) bool {
+	switch t {
+	case OfficialResponseInfoRequested, OfficialResponseAccepted, OfficialResponseInProgress,
+		OfficialResponseCompleted, OfficialResponseRejected:
+		return true
+	}
+	return false
+}
+
+// NormalizeAttachments — только файлы из /uploads/, без дублей, не больше MaxOfficialResponseAttachments.
+func NormalizeAttachments(urls []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			continue
+		}
+		if !strings.HasPrefix(u, "/uploads/") || strings.Contains(u, "..") {
+			return nil, ErrInvalidAttachment
+		}
+		seen[u] = true
+		out = append(out, u)
+	}
+	if len(out) > MaxOfficialResponseAttachments {
+		return nil, ErrInvalidAttachment
+	}
+	return out, nil
+}
+
+const officialResponseSelect = `
+		SELECT o.id, o.marker_id, o.parent_id, o.author_kind, o.department_id,
+		       COALESCE(d.name_ru, ''), COALESCE(d.icon, ''),
+		       o.responded_by, COALESCE(NULLIF(TRIM(u.display_name), ''), u.email, ''),
+		       o.response_text, o.response_type, o.planned_date, o.actual_date,
+		       COALESCE((SELECT array_agg(a.url ORDER BY a.id) FROM official_response_attachments a WHERE a.response_id = o.id), '{}'),
+		       o.created_at, o.updated_at
+		FROM official_responses o
+		LEFT JOIN departments d ON d.id = o.department_id
+		LEFT JOIN users u ON u.id = o.responded_by`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOfficialResponse(row rowScanner) (models.OfficialResponse, error) {
+	var o models.OfficialResponse
+	var parent, dept, by sql.NullInt64
+	var planned, actual sql.NullTime
+	var attachments []string
+	err := row.Scan(&o.ID, &o.MarkerID, &parent, &o.AuthorKind, &dept,
+		&o.DepartmentName, &o.DepartmentIcon, &by, &o.AuthorName,
+		&o.ResponseText, &o.ResponseType, &planned, &actual,
+		pq.Array(&attachments), &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return o, err
+	}
+	if parent.Valid {
+		v := int(parent.Int64)
+		o.ParentID = &v
+	}
+	if dept.Valid {
+		v := int(dept.Int64)
+		o.DepartmentID = &v
+	}
+	if by.Valid {
+		v := int(by.Int64)
+		o.RespondedBy = &v
+	}
+	if planned.Valid {
+		t := planned.Time
+		o.PlannedDate = &t
+	}
+	if actual.Valid {
+		t := actual.Time
+		o.ActualDate = &t
+	}
+	if attachments == nil {
+		attachments = []string{}
+	}
+	o.Attachments = attachments
+	return o, nil
+}
+
+// ListOfficialResponses — вся переписка по метке в хронологическом порядке.
+func ListOfficialResponses(markerID int) ([]models.OfficialResponse, error) {
+	rows, err := database.DB.Query(officialResponseSelect+`
+		WHERE o.marker_id = $1
+		ORDER BY o.created_at ASC, o.id ASC`, markerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []models.OfficialResponse{}
+	for rows.Next() {
+		o, err := scanOfficialResponse(rows)
+		if err != nil {
+			continue
+		}
+		list = append(list, o)
+	}
+	return list, nil
+}
+
+func GetOfficialResponse(markerID, responseID int) (models.OfficialResponse, error) {
+	return scanOfficialResponse(database.DB.QueryRow(officialResponseSelect+`
+		WHERE o.marker_id = $1 AND o.id = $2`, markerID, responseID))
+}
+
+// LatestDepartmentResponse — последний ответ ведомства (для краткой карточки метки).
+func LatestDepartmentResponse(markerID int) (models.OfficialResponse, error) {
+	return scanOfficialResponse(database.DB.QueryRow(officialResponseSelect+`
+		WHERE o.marker_id = $1 AND o.author_kind = 'department'
+		ORDER BY o.created_at DESC, o.id DESC LIMIT 1`, markerID))
+}
+
+// CreateOfficialResponse добавляет запись в переписку вместе с вложениями.
+func CreateOfficialResponse(markerID int, authorKind string, departmentID int, authorID int, req models.CreateOfficialResponseRequest) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var dept, parent interface{}
+	if departmentID > 0 {
+		dept = departmentID
+	}
+	if req.ParentID != nil && *req.ParentID > 0 {
+		parent = *req.ParentID
+	}
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO official_responses (marker_id, department_id, responded_by, response_text, response_type,
+		                                planned_date, actual_date, parent_id, author_kind)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::date, NULLIF($7, '')::date, $8, $9)
+		RETURNING id`,
+		markerID, dept, authorID, strings.TrimSpace(req.ResponseText), req.ResponseType,
+		req.PlannedDate, req.ActualDate, parent, authorKind,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	for _, url := range req.Attachments {
+		if _, err := tx.Exec(
+			`INSERT INTO official_response_attachments (response_id, url) VALUES ($1, $2)`, id, url,
+		); err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// UpdateOfficialResponse правит одну запись ведомства; пустые поля не меняются, вложения дописываются
+// в той же транзакции.
+func UpdateOfficialResponse(markerID, responseID int, text, responseType, plannedDate, actualDate string, attachments []string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`
+		UPDATE official_responses SET
+			response_text = COALESCE(NULLIF($3, ''), response_text),
+			response_type = COALESCE(NULLIF($4, ''), response_type),
+			planned_date = CASE WHEN $5 = '' THEN planned_date ELSE $5::date END,
+			actual_date = CASE WHEN $6 = '' THEN actual_date ELSE $6::date END,
+			updated_at = NOW()
+		WHERE marker_id = $1 AND id = $2 AND author_kind = 'department'`,
+		markerID, responseID, strings.TrimSpace(text), responseType, plannedDate, actualDate,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	for _, url := range attachments {
+		if _, err := tx.Exec(
+			`INSERT INTO official_response_attachments (response_id, url) VALUES ($1, $2)`, responseID, url,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,407 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/database"
+
+	"github.com/lib/pq"
+)
+
+// Статусы заданий повторной классификации.
+const (
+	ReclassifyQueued  = "queued"
+	ReclassifyRunning = "running"
+	ReclassifyDone    = "done"
+	ReclassifyFailed  = "failed"
+)
+
+const ReclassifyMaxAttempts = 5
+
+// ReclassifyScope — какие метки поставить в очередь; поля объединяются через AND, пустой scope запрещён.
+type ReclassifyScope struct {
+	MarkerIDs []int  `json:"marker_ids,omitempty"`
+	DomainKey string `json:"domain_key,omitempty"`
+	// OpenOnly — только незакрытые (pending/approved/in_progress/reopened)
+	OpenOnly bool `json:"open_only,omitempty"`
+	// Stale — domain_key пуст или отсутствует в classification_domains
+	Stale bool `json:"stale,omitempty"`
+	// All — явное согласие на все метки, если других условий нет
+	All bool `json:"all,omitempty"`
+}
+
+func (s ReclassifyScope) where() (string, []interface{}, error) {
+	var parts []string
+	var args []interface{}
+	if len(s.MarkerIDs) > 0 {
+		args = append(args, int64Array(s.MarkerIDs))
+		parts = append(parts, fmt.Sprintf("m.id = ANY($%d)", len(args)))
+	}
+	if k := strings.TrimSpace(s.DomainKey); k != "" {
+		args = append(args, k)
+		parts = append(parts, fmt.Sprintf("m.domain_key = $%d", len(args)))
+	}
+	if s.OpenOnly {
+		parts = append(parts, `LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('pending', 'approved', 'in_progress', 'reopened')`)
+	}
+	if s.Stale {
+		parts = append(parts, `(m.domain_key IS NULL OR TRIM(m.domain_key) = ''
+			OR NOT EXISTS (SELECT 1 FROM classification_domains c WHERE c.domain_key = m.domain_key))`)
+	}
+	if len(parts) == 0 {
+		if !s.All {
+			return "", nil, fmt.Errorf("empty scope: pass marker_ids, domain_key, open_only, stale or all")
+		}
+		return "TRUE", nil, nil
+	}
+	return strings.Join(parts, " AND "), args, nil
+}
+
+// EnqueueReclassification ставит метки в очередь; уже стоящие в очереди пропускаются. Возвращает число новых заданий.
+func EnqueueReclassification(scope ReclassifyScope, reason string, requestedBy *int) (int, error) {
+	where, args, err := scope.where()
+	if err != nil {
+		return 0, err
+	}
+	args = append(args, strings.TrimSpace(reason), nullActor(requestedBy))
+	res, err := database.DB.Exec(fmt.Sprintf(`
+		INSERT INTO reclassification_jobs (marker_id, reason, requested_by)
+		SELECT m.id, $%d, $%d FROM markers m
+		WHERE %s
+		ON CONFLICT DO NOTHING`, len(args)-1, len(args), where), args...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// ReclassifyJob — задание, взятое воркером.
+type ReclassifyJob struct {
+	ID       int
+	MarkerID int
+	Attempts int
+	Text     string
+	ImageURL string
+}
+
+// ClaimReclassificationJobs атомарно забирает до limit заданий; параллельные воркеры
+// (в т.ч. на других инстансах) не получают одни и те же строки благодаря SKIP LOCKED.
+func ClaimReclassificationJobs(limit int) ([]ReclassifyJob, error) {
+	if limit < 1 {
+		limit = 10
+	}
+	rows, err := database.DB.Query(`
+		WITH picked AS (
+			SELECT id FROM reclassification_jobs
+			WHERE status = 'queued' AND run_after <= NOW()
+			ORDER BY run_after, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE reclassification_jobs j
+		SET status = 'running', started_at = NOW(), attempts = j.attempts + 1
+		FROM picked, markers m
+		WHERE j.id = picked.id AND m.id = j.marker_id
+		RETURNING j.id, j.marker_id, j.attempts, m.text, COALESCE(m.image_url, '')`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []ReclassifyJob
+	for rows.Next() {
+		var j ReclassifyJob
+		if rows.Scan(&j.ID, &j.MarkerID, &j.Attempts, &j.Text, &j.ImageURL) == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs, nil
+}
+
+// CompleteReclassificationJob сохраняет предложение классификатора рядом с текущей категорией.
+func CompleteReclassificationJob(job ReclassifyJob, domainKey, groupKey, issueKey string, confidence float64) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`
+		UPDATE markers SET suggested_domain_key = $2, suggested_group_key = $3, suggested_issue_key = $4,
+		                   suggested_confidence = $5, suggested_at = NOW()
+		WHERE id = $1`, job.MarkerID, nullStr(domainKey), nullStr(groupKey), nullStr(issueKey), confidence,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		UPDATE reclassification_jobs SET status = 'done', finished_at = NOW(), last_error = NULL
+		WHERE id = $1`, job.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FailReclassificationJob возвращает задание в очередь с экспоненциальной задержкой
+// или помечает failed после ReclassifyMaxAttempts попыток.
+func FailReclassificationJob(job ReclassifyJob, cause error) error {
+	msg := ""
+	if cause != nil {
+		msg = cause.Error()
+	}
+	if job.Attempts >= ReclassifyMaxAttempts {
+		_, err := database.DB.Exec(`
+			UPDATE reclassification_jobs SET status = 'failed', finished_at = NOW(), last_error = $2
+			WHERE id = $1`, job.ID, msg)
+		return err
+	}
+	delay := time.Duration(1<<uint(job.Attempts)) * 30 * time.Second
+	_, err := database.DB.Exec(`
+		UPDATE reclassification_jobs SET status = 'queued', last_error = $2, run_after = NOW() + make_interval(secs => $3)
+		WHERE id = $1`, job.ID, msg, delay.Seconds())
+	return err
+}
+
+// RequeueStuckReclassificationJobs — задания, зависшие в running (упал воркер), снова в очередь.
+func RequeueStuckReclassificationJobs(olderThan time.Duration) (int, error) {
+	res, err := database.DB.Exec(`
+		UPDATE reclassification_jobs SET status = 'queued', run_after = NOW()
+		WHERE status = 'running' AND started_at < NOW() - make_interval(secs => $1)`, olderThan.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// ReclassificationQueueStats — число заданий по статусам.
+func ReclassificationQueueStats() (map[string]int, error) {
+	rows, err := database.DB.Query(`SELECT status, COUNT(*)::int FROM reclassification_jobs GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{ReclassifyQueued: 0, ReclassifyRunning: 0, ReclassifyDone: 0, ReclassifyFailed: 0}
+	for rows.Next() {
+		var st string
+		var n int
+		if rows.Scan(&st, &n) == nil {
+			out[st] = n
+		}
+	}
+	return out, nil
+}
+
+// ReclassificationSuggestion — метка, для которой классификатор предлагает другую категорию.
+type ReclassificationSuggestion struct {
+	MarkerID            int       `json:"marker_id"`
+	Text                string    `json:"text"`
+	Status              string    `json:"status"`
+	DomainKey           string    `json:"domain_key,omitempty"`
+	IssueKey            string    `json:"issue_key,omitempty"`
+	SuggestedDomainKey  string    `json:"suggested_domain_key"`
+	SuggestedGroupKey   string    `json:"suggested_group_key,omitempty"`
+	SuggestedIssueKey   string    `json:"suggested_issue_key,omitempty"`
+	SuggestedConfidence float64   `json:"suggested_confidence"`
+	SuggestedAt         time.Time `json:"suggested_at"`
+}
+
+const suggestionDiffersSQL = `m.suggested_domain_key IS NOT NULL
+	AND m.suggested_domain_key IS DISTINCT FROM m.domain_key`
+
+// ListReclassificationSuggestions — предложения, отличающиеся от текущей категории (сначала самые уверенные).
+func ListReclassificationSuggestions(domainKey string, minConfidence float64, page, pageSize int) ([]ReclassificationSuggestion, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+	where := suggestionDiffersSQL + ` AND COALESCE(m.suggested_confidence, 0) >= $1`
+	args := []interface{}{minConfidence}
+	if domainKey = strings.TrimSpace(domainKey); domainKey != "" {
+		where += ` AND (m.domain_key = $2 OR m.suggested_domain_key = $2)`
+		args = append(args, domainKey)
+	}
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*)::int FROM markers m WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	args = append(args, pageSize, (page-1)*pageSize)
+	rows, err := database.DB.Query(fmt.Sprintf(`
+		SELECT m.id, m.text, LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')),
+		       COALESCE(m.domain_key, ''), COALESCE(m.issue_key, ''),
+		       m.suggested_domain_key, COALESCE(m.suggested_group_key, ''), COALESCE(m.suggested_issue_key, ''),
+		       COALESCE(m.suggested_confidence, 0), m.suggested_at
+		FROM markers m WHERE %s
+		ORDER BY m.suggested_confidence DESC NULLS LAST, m.id
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list := []ReclassificationSuggestion{}
+	for rows.Next() {
+		var s ReclassificationSuggestion
+		var at sql.NullTime
+		if rows.Scan(&s.MarkerID, &s.Text, &s.Status, &s.DomainKey, &s.IssueKey,
+			&s.SuggestedDomainKey, &s.SuggestedGroupKey, &s.SuggestedIssueKey,
+			&s.SuggestedConfidence, &at) != nil {
+			continue
+		}
+		s.SuggestedAt = at.Time
+		list = append(list, s)
+	}
+	return list, total, nil
+}
+
+// AcceptReclassificationResult — итог принятия предложений.
+type AcceptReclassificationResult struct {
+	Accepted []int `json:"accepted_ids"`
+	// Stale — предложение ссылается на направление, группу или проблему, которых уже нет в дереве
+	// (дерево правили после классификации); такие предложения снимаются, категория метки не меняется.
+	Stale []int `json:"stale_ids"`
+}
+
+// AcceptReclassificationSuggestions переносит предложенную категорию в domain_key/group_key/issue_key.
+// Всё в одной транзакции: предложение проверяется по текущему дереву, назначенное ведомство переходит к
+// ведомству нового направления, изменения пишутся в marker_change_log, сроки решения пересчитываются,
+// принятое предложение попадает в разметку для обучения.
+func AcceptReclassificationSuggestions(markerIDs []int, actorID *int) (*AcceptReclassificationResult, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	type suggestion struct {
+		id                   int
+		domain, group, issue string
+	}
+	rows, err := tx.Query(`
+		SELECT m.id, m.suggested_domain_key, COALESCE(m.suggested_group_key, ''), COALESCE(m.suggested_issue_key, '')
+		FROM markers m
+		WHERE m.id = ANY($1) AND `+suggestionDiffersSQL+`
+		ORDER BY m.id
+		FOR UPDATE`, int64Array(markerIDs))
+	if err != nil {
+		return nil, err
+	}
+	var list []suggestion
+	for rows.Next() {
+		var sg suggestion
+		if err := rows.Scan(&sg.id, &sg.domain, &sg.group, &sg.issue); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, sg)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	res := &AcceptReclassificationResult{Accepted: []int{}, Stale: []int{}}
+	for _, sg := range list {
+		err := validateMarkerTaxonomyWith(tx, sg.domain, sg.group, sg.issue)
+		switch {
+		case err == nil:
+			res.Accepted = append(res.Accepted, sg.id)
+		case IsStaleTaxonomyError(err):
+			res.Stale = append(res.Stale, sg.id)
+		default:
+			return nil, err
+		}
+	}
+	if len(res.Stale) > 0 {
+		if _, err := tx.Exec(`
+			UPDATE markers SET suggested_domain_key = NULL, suggested_group_key = NULL, suggested_issue_key = NULL,
+			                   suggested_confidence = NULL, suggested_at = NULL
+			WHERE id = ANY($1)`, int64Array(res.Stale)); err != nil {
+			return nil, err
+		}
+	}
+	if len(res.Accepted) == 0 {
+		return res, tx.Commit()
+	}
+
+	// в SET m.* — значения до обновления: ведомство считается по старому и предложенному направлению
+	rows, err = tx.Query(`
+		WITH cur AS (
+			SELECT id, domain_key, department_id, suggested_confidence FROM markers WHERE id = ANY($1)
+		)
+		UPDATE markers m SET
+			domain_key = m.suggested_domain_key,
+			group_key = m.suggested_group_key,
+			issue_key = m.suggested_issue_key,
+			department_id = `+reassignedDepartmentSQL("m.suggested_domain_key")+`,
+			suggested_domain_key = NULL, suggested_group_key = NULL, suggested_issue_key = NULL,
+			suggested_confidence = NULL, suggested_at = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		FROM cur WHERE m.id = cur.id
+		RETURNING m.id, COALESCE(cur.domain_key, ''), COALESCE(m.domain_key, ''), cur.suggested_confidence,
+		          COALESCE(cur.department_id, 0), COALESCE(m.department_id, 0)`, int64Array(res.Accepted))
+	if err != nil {
+		return nil, err
+	}
+	type change struct {
+		id               int
+		old, new         string
+		conf             *float64
+		oldDept, newDept int
+	}
+	var changes []change
+	for rows.Next() {
+		var c change
+		if err := rows.Scan(&c.id, &c.old, &c.new, &c.conf, &c.oldDept, &c.newDept); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+	for _, c := range changes {
+		if err := insertMarkerChange(tx, c.id, "domain_key", c.old, c.new, actorID); err != nil {
+			return nil, err
+		}
+		if c.oldDept != c.newDept {
+			if err := insertMarkerChange(tx, c.id, "department_id", itoaOrEmpty(c.oldDept), itoaOrEmpty(c.newDept), actorID); err != nil {
+				return nil, err
+			}
+		}
+		if err := recordClassificationLabelWith(tx, c.id, c.old, LabelSuggestionAccepted, c.new, c.conf, actorID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := recomputeSLADueDatesWith(tx, actorID, res.Accepted); err != nil {
+		return nil, err
+	}
+	return res, tx.Commit()
+}
+
+// DismissReclassificationSuggestions — модератор оставляет текущую категорию.
+func DismissReclassificationSuggestions(markerIDs []int) (int, error) {
+	res, err := database.DB.Exec(`
+		UPDATE markers SET suggested_domain_key = NULL, suggested_group_key = NULL, suggested_issue_key = NULL,
+		                   suggested_confidence = NULL, suggested_at = NULL
+		WHERE id = ANY($1) AND suggested_domain_key IS NOT NULL`, int64Array(markerIDs))
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func int64Array(ids []int) interface{} {
+	out := make([]int64, len(ids))
+	for i, id := range ids {
+		out[i] = int64(id)
+	}
+	return pq.Array(out)
+}
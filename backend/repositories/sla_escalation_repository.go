@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"time"
+
+	"backend/database"
+)
+
+// Типы просроченного срока.
+const (
+	SLAKindResponse   = "response"
+	SLAKindResolution = "resolution"
+)
+
+// Уровни эскалации: модераторы и ведомство, затем администраторы.
+const (
+	SLALevelModerators = 1
+	SLALevelAdmins     = 2
+)
+
+type OverdueMarker struct {
+	MarkerID int
+	Kind     string
+	DueAt    time.Time
+	Text     string
+}
+
+// ListOverdueMarkers — метки, чей срок ответа/решения истёк раньше before и по которым ещё нет нужной
+// эскалации: уровня 1 — для всех, уровня 2 — если срок истёк раньше adminBefore (до limit штук,
+// сначала самые старые). Уже эскалированные в выборку не попадают, так что за ними идёт следующая порция.
+func ListOverdueMarkers(before, adminBefore time.Time, limit int) ([]OverdueMarker, error) {
+	if limit < 1 || limit > 1000 {
+		limit = 200
+	}
+	rows, err := database.DB.Query(`
+		SELECT id, kind, due_at, text FROM (
+			SELECT m.id, 'response' AS kind, m.response_due_at AS due_at, m.text
+			FROM markers m
+			WHERE LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) = 'pending'
+			  AND m.response_due_at IS NOT NULL AND m.response_due_at < $1
+			UNION ALL
+			SELECT m.id, 'resolution', m.resolution_due_at, m.text
+			FROM markers m
+			WHERE LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened')
+			  AND m.resolution_due_at IS NOT NULL AND m.resolution_due_at < $1
+			  AND m.sla_paused_at IS NULL
+		) o
+		WHERE NOT EXISTS (
+				SELECT 1 FROM sla_escalations e
+				WHERE e.marker_id = o.id AND e.kind = o.kind AND e.level = $4 AND e.due_at = o.due_at)
+		   OR (o.due_at < $2 AND NOT EXISTS (
+				SELECT 1 FROM sla_escalations e
+				WHERE e.marker_id = o.id AND e.kind = o.kind AND e.level = $5 AND e.due_at = o.due_at))
+		ORDER BY due_at ASC
+		LIMIT $3`, before, adminBefore, limit, SLALevelModerators, SLALevelAdmins)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []OverdueMarker
+	for rows.Next() {
+		var o OverdueMarker
+		if err := rows.Scan(&o.MarkerID, &o.Kind, &o.DueAt, &o.Text); err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
+
+// ClaimSLAEscalation фиксирует эскалацию; false — уже была (в т.ч. с другого инстанса или до рестарта).
+func ClaimSLAEscalation(markerID int, kind string, level int, dueAt time.Time) (bool, error) {
+	res, err := database.DB.Exec(`
+		INSERT INTO sla_escalations (marker_id, kind, level, due_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`, markerID, kind, level, dueAt)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// StaffUserIDs — модераторы (adminsOnly=false, включая админов) или только администраторы.
+func StaffUserIDs(adminsOnly bool) ([]int, error) {
+	q := `SELECT id FROM users WHERE COALESCE(is_moderator, FALSE) OR COALESCE(is_admin, FALSE)`
+	if adminsOnly {
+		q = `SELECT id FROM users WHERE COALESCE(is_admin, FALSE)`
+	}
+	rows, err := database.DB.Query(q + ` ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
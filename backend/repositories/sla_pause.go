@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"backend/database"
+)
+
+// OfficialResponseInfoRequested — ведомство запросило уточнения у заявителя; срок решения на паузе.
+const OfficialResponseInfoRequested = "info_requested"
+
+func nullActor(actorID *int) interface{} {
+	if actorID != nil && *actorID > 0 {
+		return *actorID
+	}
+	return nil
+}
+
+// PauseMarkerSLA ставит срок решения на паузу. false — метка не в работе или уже на паузе.
+func PauseMarkerSLA(markerID, officialResponseID int, actorID *int) (bool, error) {
+	var pausedAt time.Time
+	err := database.DB.QueryRow(`
+		UPDATE markers SET sla_paused_at = NOW()
+		WHERE id = $1 AND sla_paused_at IS NULL
+		  AND LOWER(COALESCE(NULLIF(TRIM(status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened')
+		RETURNING sla_paused_at`, markerID,
+	).Scan(&pausedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var resp interface{}
+	if officialResponseID > 0 {
+		resp = officialResponseID
+	}
+	_, _ = database.DB.Exec(`
+		INSERT INTO sla_pauses (marker_id, official_response_id, paused_by, paused_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`, markerID, resp, nullActor(actorID), pausedAt)
+	_ = InsertMarkerChange(markerID, "sla_paused", "", pausedAt.Format(time.RFC3339), actorID)
+	return true, nil
+}
+
+// ResumeMarkerSLA снимает паузу: срок решения сдвигается на длительность паузы.
+// Возвращает длительность паузы (0 — метка не была на паузе).
+func ResumeMarkerSLA(markerID int, actorID *int) (time.Duration, error) {
+	return resumeMarkerSLAWith(database.DB, markerID, actorID)
+}
+
+// resumeMarkerSLAWith — снятие паузы на q (в т.ч. внутри смены статуса).
+func resumeMarkerSLAWith(q dbExecutor, markerID int, actorID *int) (time.Duration, error) {
+	var seconds int64
+	var newDue sql.NullTime
+	err := q.QueryRow(`
+		WITH p AS (
+			SELECT id, GREATEST(EXTRACT(EPOCH FROM NOW() - sla_paused_at), 0)::bigint AS secs
+			FROM markers WHERE id = $1 AND sla_paused_at IS NOT NULL
+			FOR UPDATE
+		)
+		UPDATE markers m SET
+			resolution_due_at = m.resolution_due_at + make_interval(secs => p.secs),
+			sla_paused_seconds = m.sla_paused_seconds + p.secs,
+			sla_paused_at = NULL
+		FROM p WHERE m.id = p.id
+		RETURNING p.secs, m.resolution_due_at`, markerID,
+	).Scan(&seconds, &newDue)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := q.Exec(`
+		UPDATE sla_pauses SET resumed_at = NOW(), resumed_by = $2
+		WHERE marker_id = $1 AND resumed_at IS NULL`, markerID, nullActor(actorID)); err != nil {
+		return 0, err
+	}
+	d := time.Duration(seconds) * time.Second
+	newVal := d.String()
+	if newDue.Valid {
+		newVal = newDue.Time.Format(time.RFC3339)
+	}
+	if err := insertMarkerChange(q, markerID, "sla_resumed", "", newVal, actorID); err != nil {
+		return 0, err
+	}
+	return d, nil
+}
+
+// SLAPauseTotals — метки на паузе сейчас и суммарное время пауз (часы) по завершённым паузам.
+func SLAPauseTotals() (pausedNow int, pausedHours int) {
+	_ = database.DB.QueryRow(`SELECT COUNT(*) FROM markers WHERE sla_paused_at IS NOT NULL`).Scan(&pausedNow)
+	_ = database.DB.QueryRow(`
+		SELECT COALESCE(SUM(EXTRACT(EPOCH FROM resumed_at - paused_at)), 0)::bigint / 3600
+		FROM sla_pauses WHERE resumed_at IS NOT NULL`).Scan(&pausedHours)
+	return pausedNow, pausedHours
+}
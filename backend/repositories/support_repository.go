@@ -96,7 +96,7 @@ func (r *SupportRepository) FindNearby(lat, lng float64, radiusM int, limit int)
 		       m.latitude, m.longitude,
 		       (SELECT COUNT(*)::int FROM marker_supports s WHERE s.marker_id = m.id)
 		FROM markers m
-		WHERE LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'pending')
+		WHERE LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened', 'pending')
 		  AND m.latitude BETWEEN $1 AND $2
 		  AND m.longitude BETWEEN $3 AND $4
 	`, lat-delta, lat+delta, lng-delta, lng+delta)
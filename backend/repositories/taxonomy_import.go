@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+)
+
+// ValidateTaxonomyImport проверяет и нормализует импортируемую таксономию на месте:
+// ключи — через ValidateDomainKey, иконки — через NormalizeMarkerIcon, пустые фразы отбрасываются.
+// errs блокируют импорт, warnings — исправленные значения.
+func ValidateTaxonomyImport(tax *models.Taxonomy) (errs, warnings []string) {
+	if tax == nil || len(tax.Domains) == 0 {
+		return []string{"Пустая таксономия: нет направлений"}, nil
+	}
+	seen := map[string]bool{}
+	for i := range tax.Domains {
+		d := &tax.Domains[i]
+		d.Key = strings.TrimSpace(d.Key)
+		d.LabelRu = strings.TrimSpace(d.LabelRu)
+		where := fmt.Sprintf("domains[%d] %s", i, d.Key)
+		if err := ValidateDomainKey(d.Key); err != nil {
+			errs = append(errs, where+": "+err.Error())
+		} else if seen[d.Key] {
+			errs = append(errs, where+": ключ повторяется")
+		}
+		seen[d.Key] = true
+		if d.LabelRu == "" {
+			errs = append(errs, where+": нет названия")
+		}
+		icon := strings.TrimSpace(d.MarkerIcon)
+		switch {
+		case icon == "":
+			d.MarkerIcon = defaultSeedIcons[i%len(defaultSeedIcons)]
+		case NormalizeMarkerIcon(icon) != icon:
+			d.MarkerIcon = NormalizeMarkerIcon(icon)
+			warnings = append(warnings, fmt.Sprintf("%s: иконка %q заменена на %s", where, icon, d.MarkerIcon))
+		}
+		if d.ResolutionDays < 1 {
+			if d.ResolutionDays < 0 {
+				warnings = append(warnings, fmt.Sprintf("%s: resolution_days %d заменён на значение по умолчанию", where, d.ResolutionDays))
+			}
+			// не указан — остаётся текущий срок направления (или срок по умолчанию для нового)
+			d.ResolutionDays = ResolutionDaysForDomain(d.Key)
+		}
+		d.TrainingPhrasesRu = cleanPhrases(d.TrainingPhrasesRu)
+
+		seenGroups := map[string]bool{}
+		for gi := range d.Groups {
+			g := &d.Groups[gi]
+			g.Key, g.LabelRu = strings.TrimSpace(g.Key), strings.TrimSpace(g.LabelRu)
+			gw := fmt.Sprintf("%s/%s", d.Key, g.Key)
+			if err := ValidateDomainKey(g.Key); err != nil {
+				errs = append(errs, gw+": "+err.Error())
+			} else if seenGroups[g.Key] {
+				errs = append(errs, gw+": ключ группы повторяется")
+			}
+			seenGroups[g.Key] = true
+			if g.LabelRu == "" {
+				errs = append(errs, gw+": нет названия")
+			}
+			g.TrainingPhrasesRu = cleanPhrases(g.TrainingPhrasesRu)
+
+			seenIssues := map[string]bool{}
+			for ii := range g.Issues {
+				is := &g.Issues[ii]
+				is.Key, is.LabelRu = strings.TrimSpace(is.Key), strings.TrimSpace(is.LabelRu)
+				iw := gw + "/" + is.Key
+				if err := ValidateDomainKey(is.Key); err != nil {
+					errs = append(errs, iw+": "+err.Error())
+				} else if seenIssues[is.Key] {
+					errs = append(errs, iw+": ключ проблемы повторяется")
+				}
+				seenIssues[is.Key] = true
+				if is.LabelRu == "" {
+					errs = append(errs, iw+": нет названия")
+				}
+				is.TrainingPhrasesRu = cleanPhrases(is.TrainingPhrasesRu)
+			}
+		}
+	}
+	return errs, warnings
+}
+
+func cleanPhrases(phrases []string) []string {
+	var out []string
+	for _, p := range phrases {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// MarkersOrphanedByTaxonomy — обращения, чьи категории исчезнут при замене таксономии на tax (ключ — путь категории).
+func MarkersOrphanedByTaxonomy(tax *models.Taxonomy) (map[string]int, error) {
+	return markersOrphanedByTaxonomy(database.DB, tax)
+}
+
+// ReplaceTaxonomy заменяет таксономию на tax одной транзакцией вместе со снимком новой версии
+// (reason — причина версии) и переписывает issue-taxonomy.json. Возвращает номер версии (0 — изменений нет).
+func ReplaceTaxonomy(tax *models.Taxonomy, actorID *int, reason string) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`LOCK TABLE classification_domains, classification_groups, classification_issues IN EXCLUSIVE MODE`); err != nil {
+		return 0, err
+	}
+	if err := applyTaxonomyTx(tx, tax); err != nil {
+		return 0, err
+	}
+	version, err := snapshotTaxonomyWith(tx, actorID, reason)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return version, SyncTaxonomyFile()
+}
@@ -120,6 +120,10 @@ func SeedClassificationsIfEmpty() {
 		)
 		if err != nil {
 			log.Printf("classification seed insert %s: %v", d.Key, err)
+			continue
+		}
+		if err := seedTaxonomyGroups(d.Key, d.Groups); err != nil {
+			log.Printf("classification seed groups %s: %v", d.Key, err)
 		}
 	}
 	log.Println("Классификации загружены из issue-taxonomy.json")
@@ -127,7 +131,17 @@ func SeedClassificationsIfEmpty() {
 }
 
 func ListTaxonomy() (*models.Taxonomy, error) {
-	rows, err := database.DB.Query(`
+	return listTaxonomyWith(database.DB)
+}
+
+// listTaxonomyWith — таксономия на q (в т.ч. внутри транзакции замены): группы читаются
+// до направлений, чтобы на соединении транзакции не было двух открытых выборок.
+func listTaxonomyWith(q dbExecutor) (*models.Taxonomy, error) {
+	groups, err := loadTaxonomyGroupsWith(q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(`
 		SELECT domain_key, label_ru, marker_icon, training_phrases, resolution_days, sort_order
 		FROM classification_domains
 		ORDER BY sort_order ASC, domain_key ASC
@@ -157,6 +171,7 @@ func ListTaxonomy() (*models.Taxonomy, error) {
 			MarkerIcon:        icon,
 			ResolutionDays:    resDays,
 			TrainingPhrasesRu: phrases,
+			Groups:            groups[key],
 		})
 	}
 	return tax, nil
@@ -165,37 +180,54 @@ func ListTaxonomy() (*models.Taxonomy, error) {
 func ListAdminClassifications() ([]models.AdminClassificationRow, error) {
 	rows, err := database.DB.Query(`
 		SELECT c.domain_key, c.label_ru, c.marker_icon, c.resolution_days, c.sort_order, c.training_phrases,
+			c.duplicate_threshold, c.duplicate_radius_m,
 			COUNT(m.id)::int,
 			COUNT(*) FILTER (WHERE LOWER(COALESCE(m.status, '')) = 'resolved')::int,
 			COUNT(*) FILTER (WHERE
 				(LOWER(COALESCE(m.status, '')) = 'pending'
 					AND m.response_due_at IS NOT NULL AND m.response_due_at < NOW())
-				OR (LOWER(COALESCE(m.status, '')) IN ('approved', 'in_progress')
-					AND m.resolution_due_at IS NOT NULL AND m.resolution_due_at < NOW())
+				OR (LOWER(COALESCE(m.status, '')) IN ('approved', 'in_progress', 'reopened')
+					AND m.resolution_due_at IS NOT NULL AND m.resolution_due_at < NOW() AND m.sla_paused_at IS NULL)
 			)::int,
 			AVG(EXTRACT(EPOCH FROM (m.resolved_at - m.created_at)) / 86400.0)
 				FILTER (WHERE m.resolved_at IS NOT NULL)
 		FROM classification_domains c
 		LEFT JOIN markers m ON m.domain_key = c.domain_key
-		GROUP BY c.domain_key, c.label_ru, c.marker_icon, c.resolution_days, c.sort_order, c.training_phrases
+		GROUP BY c.domain_key, c.label_ru, c.marker_icon, c.resolution_days, c.sort_order, c.training_phrases,
+			c.duplicate_threshold, c.duplicate_radius_m
 		ORDER BY c.sort_order ASC, c.domain_key ASC
 	`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	groups, err := loadTaxonomyGroups()
+	if err != nil {
+		return nil, err
+	}
 	var list []models.AdminClassificationRow
 	for rows.Next() {
 		var row models.AdminClassificationRow
-		var avgDays sql.NullFloat64
+		var avgDays, dupThreshold sql.NullFloat64
+		var dupRadius sql.NullInt64
 		var phrasesJSON []byte
 		if err := rows.Scan(
 			&row.Key, &row.LabelRu, &row.MarkerIcon, &row.ResolutionDays, &row.SortOrder, &phrasesJSON,
+			&dupThreshold, &dupRadius,
 			&row.MarkersCount, &row.ResolvedCount, &row.OverdueCount, &avgDays,
 		); err != nil {
 			continue
 		}
 		_ = json.Unmarshal(phrasesJSON, &row.TrainingPhrasesRu)
+		row.Groups = groups[row.Key]
+		if dupThreshold.Valid {
+			v := dupThreshold.Float64
+			row.DuplicateThreshold = &v
+		}
+		if dupRadius.Valid {
+			v := int(dupRadius.Int64)
+			row.DuplicateRadiusM = &v
+		}
 		if row.MarkersCount > 0 {
 			row.ResolvedPct = float64(row.ResolvedCount) * 100 / float64(row.MarkersCount)
 		}
@@ -322,6 +354,24 @@ func UpdateClassification(key string, req models.UpdateClassificationRequest) er
 		args = append(args, *req.SortOrder)
 		n++
 	}
+	if req.DuplicateThreshold != nil {
+		v := *req.DuplicateThreshold
+		if v < 0 || v > 1 {
+			return errors.New("Порог дублей: от 0 до 1")
+		}
+		sets = append(sets, "duplicate_threshold = $"+itoa(n))
+		args = append(args, sql.NullFloat64{Float64: v, Valid: v > 0})
+		n++
+	}
+	if req.DuplicateRadiusM != nil {
+		v := *req.DuplicateRadiusM
+		if v < 0 || v > 5000 {
+			return errors.New("Радиус поиска дублей: от 0 до 5000 м")
+		}
+		sets = append(sets, "duplicate_radius_m = $"+itoa(n))
+		args = append(args, sql.NullInt64{Int64: int64(v), Valid: v > 0})
+		n++
+	}
 	if len(sets) == 0 {
 		return errors.New("Нечего обновлять")
 	}
@@ -338,26 +388,153 @@ func UpdateClassification(key string, req models.UpdateClassificationRequest) er
 	return SyncTaxonomyFile()
 }
 
-func DeleteClassification(key string) error {
+// DomainDeletion — что делать с обращениями удаляемого направления: перенести в TargetDomainKey
+// или явно оставить без категории (Orphan). Без одного из вариантов направление с обращениями не удаляется.
+type DomainDeletion struct {
+	TargetDomainKey string
+	Orphan          bool
+}
+
+// DomainInUseError — у направления есть обращения, а перенос не указан.
+type DomainInUseError struct {
+	Markers int
+}
+
+func (e *DomainInUseError) Error() string {
+	return "Нельзя удалить: есть обращения с этой классификацией (" + itoa(e.Markers) +
+		") — укажите направление для переноса или явно оставьте их без категории"
+}
+
+// DeleteClassification удаляет направление. Обращения переносятся в той же транзакции одним проходом:
+// назначенное ведомство переходит к ведомству нового направления, каждое изменение
+// domain_key/group_key/issue_key/department_id пишется в marker_change_log, сроки решения пересчитываются.
+// Возвращает id перенесённых обращений.
+func DeleteClassification(key string, opt DomainDeletion, actorID *int) ([]int, error) {
 	key = strings.TrimSpace(key)
-	var cnt int
-	if err := database.DB.QueryRow(
-		`SELECT COUNT(*) FROM markers WHERE domain_key = $1`, key,
-	).Scan(&cnt); err != nil {
-		return err
+	target := strings.TrimSpace(opt.TargetDomainKey)
+	if target != "" && opt.Orphan {
+		return nil, errors.New("Укажите либо направление для переноса, либо orphan")
+	}
+	if target == key && key != "" {
+		return nil, errors.New("Нельзя перенести обращения в удаляемое направление")
 	}
-	if cnt > 0 {
-		return errors.New("Нельзя удалить: есть обращения с этой классификацией")
+	// календари читаются до транзакции: пересчёт сроков внутри неё берёт их из кэша, а не из других соединений
+	LoadWorkCalendar(0)
+	if target != "" {
+		LoadWorkCalendar(DepartmentIDForDomain(target))
 	}
-	res, err := database.DB.Exec(`DELETE FROM classification_domains WHERE domain_key = $1`, key)
+	tx, err := database.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	aff, _ := res.RowsAffected()
-	if aff == 0 {
-		return sql.ErrNoRows
+	defer tx.Rollback()
+	var locked string
+	if err := tx.QueryRow(
+		`SELECT domain_key FROM classification_domains WHERE domain_key = $1 FOR UPDATE`, key,
+	).Scan(&locked); err != nil {
+		return nil, err
 	}
-	return SyncTaxonomyFile()
+	if target != "" {
+		if err := tx.QueryRow(
+			`SELECT domain_key FROM classification_domains WHERE domain_key = $1 FOR SHARE`, target,
+		).Scan(&locked); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrUnknownDomain
+			}
+			return nil, err
+		}
+	}
+
+	type moved struct {
+		id           int
+		group, issue string
+		dept         int
+	}
+	rows, err := tx.Query(`
+		SELECT id, COALESCE(group_key, ''), COALESCE(issue_key, ''), COALESCE(department_id, 0) FROM markers
+		WHERE domain_key = $1 ORDER BY id FOR UPDATE`, key)
+	if err != nil {
+		return nil, err
+	}
+	var list []moved
+	for rows.Next() {
+		var m moved
+		if err := rows.Scan(&m.id, &m.group, &m.issue, &m.dept); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+	if len(list) > 0 && target == "" && !opt.Orphan {
+		return nil, &DomainInUseError{Markers: len(list)}
+	}
+
+	ids := make([]int, 0, len(list))
+	if len(list) > 0 {
+		// группы и проблемы принадлежат удаляемому направлению — у перенесённых обращений они сбрасываются
+		rows, err := tx.Query(`
+			UPDATE markers m SET domain_key = $2, group_key = NULL, issue_key = NULL,
+				department_id = `+reassignedDepartmentSQL("$2::text")+`,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE m.domain_key = $1
+			RETURNING m.id, COALESCE(m.department_id, 0)`, key, nullStr(target))
+		if err != nil {
+			return nil, err
+		}
+		newDept := make(map[int]int, len(list))
+		for rows.Next() {
+			var id, dept int
+			if err := rows.Scan(&id, &dept); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			newDept[id] = dept
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+
+		changes := make([]markerChange, 0, len(list)*2)
+		for _, m := range list {
+			ids = append(ids, m.id)
+			changes = append(changes, markerChange{m.id, "domain_key", key, target})
+			if m.group != "" {
+				changes = append(changes, markerChange{m.id, "group_key", m.group, ""})
+			}
+			if m.issue != "" {
+				changes = append(changes, markerChange{m.id, "issue_key", m.issue, ""})
+			}
+			if d, ok := newDept[m.id]; ok && d != m.dept {
+				changes = append(changes, markerChange{m.id, "department_id", itoaOrEmpty(m.dept), itoaOrEmpty(d)})
+			}
+		}
+		if err := insertMarkerChanges(tx, changes, actorID); err != nil {
+			return nil, err
+		}
+		if _, err := recomputeSLADueDatesWith(tx, actorID, ids); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(`
+		UPDATE markers SET suggested_domain_key = NULL, suggested_group_key = NULL, suggested_issue_key = NULL,
+		                   suggested_confidence = NULL, suggested_at = NULL
+		WHERE suggested_domain_key = $1`, key); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM classification_domains WHERE domain_key = $1`, key); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, SyncTaxonomyFile()
 }
 
 func AllowedMarkerIconsList() []string {
@@ -0,0 +1,349 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+)
+
+var (
+	ErrUnknownGroup      = errors.New("unknown group_key")
+	ErrUnknownIssue      = errors.New("unknown issue_key")
+	ErrIssueWithoutGroup = errors.New("issue_key requires group_key")
+)
+
+// loadTaxonomyGroups — группы с проблемами по направлениям, в порядке sort_order.
+func loadTaxonomyGroups() (map[string][]models.TaxonomyGroup, error) {
+	return loadTaxonomyGroupsWith(database.DB)
+}
+
+func loadTaxonomyGroupsWith(q dbExecutor) (map[string][]models.TaxonomyGroup, error) {
+	rows, err := q.Query(`
+		SELECT domain_key, group_key, label_ru, training_phrases
+		FROM classification_groups
+		ORDER BY domain_key, sort_order ASC, group_key ASC`)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string][]models.TaxonomyGroup{}
+	for rows.Next() {
+		var domain string
+		var g models.TaxonomyGroup
+		var phrasesJSON []byte
+		if rows.Scan(&domain, &g.Key, &g.LabelRu, &phrasesJSON) != nil {
+			continue
+		}
+		_ = json.Unmarshal(phrasesJSON, &g.TrainingPhrasesRu)
+		out[domain] = append(out[domain], g)
+	}
+	rows.Close()
+
+	rows, err = q.Query(`
+		SELECT domain_key, group_key, issue_key, label_ru, training_phrases
+		FROM classification_issues
+		ORDER BY domain_key, group_key, sort_order ASC, issue_key ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var domain, group string
+		var is models.TaxonomyIssue
+		var phrasesJSON []byte
+		if rows.Scan(&domain, &group, &is.Key, &is.LabelRu, &phrasesJSON) != nil {
+			continue
+		}
+		_ = json.Unmarshal(phrasesJSON, &is.TrainingPhrasesRu)
+		groups := out[domain]
+		for i := range groups {
+			if groups[i].Key == group {
+				groups[i].Issues = append(groups[i].Issues, is)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// ValidateMarkerTaxonomy проверяет, что group_key/issue_key существуют внутри своего направления.
+// Пустой domain_key допустим (категорию определит классификатор), пустые group/issue — тоже.
+func ValidateMarkerTaxonomy(domainKey, groupKey, issueKey string) error {
+	return validateMarkerTaxonomyWith(database.DB, domainKey, groupKey, issueKey)
+}
+
+// IsStaleTaxonomyError — ключи не найдены в текущем дереве (в отличие от ошибки БД).
+func IsStaleTaxonomyError(err error) bool {
+	return errors.Is(err, ErrUnknownDomain) || errors.Is(err, ErrUnknownGroup) ||
+		errors.Is(err, ErrUnknownIssue) || errors.Is(err, ErrIssueWithoutGroup)
+}
+
+func validateMarkerTaxonomyWith(q dbExecutor, domainKey, groupKey, issueKey string) error {
+	domainKey, groupKey, issueKey = strings.TrimSpace(domainKey), strings.TrimSpace(groupKey), strings.TrimSpace(issueKey)
+	if issueKey != "" && groupKey == "" {
+		return ErrIssueWithoutGroup
+	}
+	if domainKey == "" {
+		if groupKey != "" {
+			return ErrUnknownGroup
+		}
+		return nil
+	}
+	var domainOK, groupOK, issueOK bool
+	err := q.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM classification_domains WHERE domain_key = $1),
+		       $2 = '' OR EXISTS (SELECT 1 FROM classification_groups WHERE domain_key = $1 AND group_key = $2),
+		       $3 = '' OR EXISTS (SELECT 1 FROM classification_issues WHERE domain_key = $1 AND group_key = $2 AND issue_key = $3)`,
+		domainKey, groupKey, issueKey,
+	).Scan(&domainOK, &groupOK, &issueOK)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !domainOK:
+		return ErrUnknownDomain
+	case !groupOK:
+		return ErrUnknownGroup
+	case !issueOK:
+		return ErrUnknownIssue
+	}
+	return nil
+}
+
+func nextGroupSortOrder(domainKey string) int {
+	var n int
+	_ = database.DB.QueryRow(
+		`SELECT COALESCE(MAX(sort_order), -1) + 1 FROM classification_groups WHERE domain_key = $1`, domainKey,
+	).Scan(&n)
+	return n
+}
+
+func nextIssueSortOrder(domainKey, groupKey string) int {
+	var n int
+	_ = database.DB.QueryRow(
+		`SELECT COALESCE(MAX(sort_order), -1) + 1 FROM classification_issues WHERE domain_key = $1 AND group_key = $2`,
+		domainKey, groupKey,
+	).Scan(&n)
+	return n
+}
+
+func validateTaxonomyNode(req models.CreateTaxonomyNodeRequest) (string, string, string, error) {
+	key := strings.TrimSpace(req.Key)
+	label := strings.TrimSpace(req.LabelRu)
+	if label == "" {
+		return "", "", "", errors.New("Укажите название")
+	}
+	if err := ValidateDomainKey(key); err != nil {
+		return "", "", "", err
+	}
+	return key, label, phrasesJSON(req.TrainingPhrasesRu), nil
+}
+
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique")
+}
+
+// CreateTaxonomyGroup добавляет группу в направление; sql.ErrNoRows — направления нет.
+func CreateTaxonomyGroup(domainKey string, req models.CreateTaxonomyNodeRequest) error {
+	domainKey = strings.TrimSpace(domainKey)
+	key, label, phrases, err := validateTaxonomyNode(req)
+	if err != nil {
+		return err
+	}
+	res, err := database.DB.Exec(`
+		INSERT INTO classification_groups (domain_key, group_key, label_ru, training_phrases, sort_order)
+		SELECT domain_key, $2, $3, $4::jsonb, $5 FROM classification_domains WHERE domain_key = $1`,
+		domainKey, key, label, phrases, nextGroupSortOrder(domainKey),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errors.New("Группа с таким ключом уже существует")
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return SyncTaxonomyFile()
+}
+
+// CreateTaxonomyIssue добавляет проблему в группу; sql.ErrNoRows — группы нет.
+func CreateTaxonomyIssue(domainKey, groupKey string, req models.CreateTaxonomyNodeRequest) error {
+	domainKey, groupKey = strings.TrimSpace(domainKey), strings.TrimSpace(groupKey)
+	key, label, phrases, err := validateTaxonomyNode(req)
+	if err != nil {
+		return err
+	}
+	res, err := database.DB.Exec(`
+		INSERT INTO classification_issues (domain_key, group_key, issue_key, label_ru, training_phrases, sort_order)
+		SELECT domain_key, group_key, $3, $4, $5::jsonb, $6 FROM classification_groups
+		WHERE domain_key = $1 AND group_key = $2`,
+		domainKey, groupKey, key, label, phrases, nextIssueSortOrder(domainKey, groupKey),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errors.New("Проблема с таким ключом уже существует")
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return SyncTaxonomyFile()
+}
+
+func taxonomyNodeSets(req models.UpdateTaxonomyNodeRequest) ([]string, []interface{}, error) {
+	var sets []string
+	var args []interface{}
+	if req.LabelRu != nil {
+		label := strings.TrimSpace(*req.LabelRu)
+		if label == "" {
+			return nil, nil, errors.New("Название не может быть пустым")
+		}
+		args = append(args, label)
+		sets = append(sets, "label_ru = $"+itoa(len(args)))
+	}
+	if req.TrainingPhrasesRu != nil {
+		phrases, _ := json.Marshal(req.TrainingPhrasesRu)
+		args = append(args, string(phrases))
+		sets = append(sets, "training_phrases = $"+itoa(len(args))+"::jsonb")
+	}
+	if req.SortOrder != nil && *req.SortOrder >= 0 {
+		args = append(args, *req.SortOrder)
+		sets = append(sets, "sort_order = $"+itoa(len(args)))
+	}
+	if len(sets) == 0 {
+		return nil, nil, errors.New("Нечего обновлять")
+	}
+	return sets, args, nil
+}
+
+func UpdateTaxonomyGroup(domainKey, groupKey string, req models.UpdateTaxonomyNodeRequest) error {
+	sets, args, err := taxonomyNodeSets(req)
+	if err != nil {
+		return err
+	}
+	args = append(args, strings.TrimSpace(domainKey), strings.TrimSpace(groupKey))
+	n := len(args)
+	return execTaxonomyNodeChange(`UPDATE classification_groups SET `+strings.Join(sets, ", ")+
+		` WHERE domain_key = $`+itoa(n-1)+` AND group_key = $`+itoa(n), args...)
+}
+
+func UpdateTaxonomyIssue(domainKey, groupKey, issueKey string, req models.UpdateTaxonomyNodeRequest) error {
+	sets, args, err := taxonomyNodeSets(req)
+	if err != nil {
+		return err
+	}
+	args = append(args, strings.TrimSpace(domainKey), strings.TrimSpace(groupKey), strings.TrimSpace(issueKey))
+	n := len(args)
+	return execTaxonomyNodeChange(`UPDATE classification_issues SET `+strings.Join(sets, ", ")+
+		` WHERE domain_key = $`+itoa(n-2)+` AND group_key = $`+itoa(n-1)+` AND issue_key = $`+itoa(n), args...)
+}
+
+// DeleteTaxonomyGroup удаляет группу вместе с проблемами, если на неё не ссылаются обращения.
+func DeleteTaxonomyGroup(domainKey, groupKey string) error {
+	domainKey, groupKey = strings.TrimSpace(domainKey), strings.TrimSpace(groupKey)
+	var cnt int
+	if err := database.DB.QueryRow(
+		`SELECT COUNT(*) FROM markers WHERE domain_key = $1 AND group_key = $2`, domainKey, groupKey,
+	).Scan(&cnt); err != nil {
+		return err
+	}
+	if cnt > 0 {
+		return errors.New("Нельзя удалить: есть обращения с этой группой")
+	}
+	return execTaxonomyNodeChange(`DELETE FROM classification_groups WHERE domain_key = $1 AND group_key = $2`,
+		domainKey, groupKey)
+}
+
+func DeleteTaxonomyIssue(domainKey, groupKey, issueKey string) error {
+	domainKey, groupKey, issueKey = strings.TrimSpace(domainKey), strings.TrimSpace(groupKey), strings.TrimSpace(issueKey)
+	var cnt int
+	if err := database.DB.QueryRow(
+		`SELECT COUNT(*) FROM markers WHERE domain_key = $1 AND group_key = $2 AND issue_key = $3`,
+		domainKey, groupKey, issueKey,
+	).Scan(&cnt); err != nil {
+		return err
+	}
+	if cnt > 0 {
+		return errors.New("Нельзя удалить: есть обращения с этой проблемой")
+	}
+	return execTaxonomyNodeChange(`DELETE FROM classification_issues WHERE domain_key = $1 AND group_key = $2 AND issue_key = $3`,
+		domainKey, groupKey, issueKey)
+}
+
+func execTaxonomyNodeChange(q string, args ...interface{}) error {
+	res, err := database.DB.Exec(q, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return SyncTaxonomyFile()
+}
+
+func ReorderTaxonomyGroups(domainKey string, keys []string) error {
+	return reorderTaxonomyNodes(keys,
+		`UPDATE classification_groups SET sort_order = $1 WHERE group_key = $2 AND domain_key = $3`,
+		strings.TrimSpace(domainKey))
+}
+
+func ReorderTaxonomyIssues(domainKey, groupKey string, keys []string) error {
+	return reorderTaxonomyNodes(keys,
+		`UPDATE classification_issues SET sort_order = $1 WHERE issue_key = $2 AND domain_key = $3 AND group_key = $4`,
+		strings.TrimSpace(domainKey), strings.TrimSpace(groupKey))
+}
+
+func reorderTaxonomyNodes(keys []string, q string, parent ...interface{}) error {
+	if len(keys) == 0 {
+		return errors.New("Пустой порядок")
+	}
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		args := append([]interface{}{i, key}, parent...)
+		if _, err := tx.Exec(q, args...); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return SyncTaxonomyFile()
+}
+
+// seedTaxonomyGroups загружает группы и проблемы направления из issue-taxonomy.json.
+func seedTaxonomyGroups(domainKey string, groups []models.TaxonomyGroup) error {
+	for gi, g := range groups {
+		if _, err := database.DB.Exec(`
+			INSERT INTO classification_groups (domain_key, group_key, label_ru, training_phrases, sort_order)
+			VALUES ($1, $2, $3, $4::jsonb, $5)
+			ON CONFLICT (domain_key, group_key) DO NOTHING`,
+			domainKey, g.Key, g.LabelRu, phrasesJSON(g.TrainingPhrasesRu), gi,
+		); err != nil {
+			return err
+		}
+		for ii, is := range g.Issues {
+			if _, err := database.DB.Exec(`
+				INSERT INTO classification_issues (domain_key, group_key, issue_key, label_ru, training_phrases, sort_order)
+				VALUES ($1, $2, $3, $4, $5::jsonb, $6)
+				ON CONFLICT (domain_key, group_key, issue_key) DO NOTHING`,
+				domainKey, g.Key, is.Key, is.LabelRu, phrasesJSON(is.TrainingPhrasesRu), ii,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
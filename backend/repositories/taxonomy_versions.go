@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/lib/pq"
+)
+
+// TaxonomyVersion — снимок таксономии после изменения.
+type TaxonomyVersion struct {
+	Version   int              `json:"version"`
+	Reason    string           `json:"reason,omitempty"`
+	CreatedBy *int             `json:"created_by,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	Snapshot  *models.Taxonomy `json:"snapshot,omitempty"`
+}
+
+// SnapshotTaxonomy сохраняет текущую таксономию новой версией, если она отличается от последней.
+// Возвращает номер версии (0 — изменений нет).
+func SnapshotTaxonomy(actorID *int, reason string) (int, error) {
+	return snapshotTaxonomyWith(database.DB, actorID, reason)
+}
+
+// snapshotTaxonomyWith — снимок на q: в транзакции замены версия пишется вместе с изменением.
+func snapshotTaxonomyWith(q dbExecutor, actorID *int, reason string) (int, error) {
+	tax, err := listTaxonomyWith(q)
+	if err != nil {
+		return 0, err
+	}
+	raw, err := json.Marshal(tax)
+	if err != nil {
+		return 0, err
+	}
+	var version int
+	err = q.QueryRow(`
+		INSERT INTO taxonomy_versions (snapshot, reason, created_by)
+		SELECT $1::jsonb, $2, $3
+		WHERE NOT EXISTS (
+			SELECT 1 FROM (SELECT snapshot FROM taxonomy_versions ORDER BY version DESC LIMIT 1) last
+			WHERE last.snapshot = $1::jsonb
+		)
+		RETURNING version`, string(raw), nullStr(strings.TrimSpace(reason)), nullActor(actorID),
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+func ListTaxonomyVersions(limit int) ([]TaxonomyVersion, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	rows, err := database.DB.Query(`
+		SELECT version, COALESCE(reason, ''), created_by, created_at
+		FROM taxonomy_versions ORDER BY version DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []TaxonomyVersion{}
+	for rows.Next() {
+		var v TaxonomyVersion
+		var by sql.NullInt64
+		if rows.Scan(&v.Version, &v.Reason, &by, &v.CreatedAt) != nil {
+			continue
+		}
+		if by.Valid {
+			id := int(by.Int64)
+			v.CreatedBy = &id
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// GetTaxonomyVersion — версия со снимком; sql.ErrNoRows, если её нет.
+func GetTaxonomyVersion(version int) (*TaxonomyVersion, error) {
+	v := &TaxonomyVersion{}
+	var by sql.NullInt64
+	var raw []byte
+	err := database.DB.QueryRow(`
+		SELECT version, COALESCE(reason, ''), created_by, created_at, snapshot
+		FROM taxonomy_versions WHERE version = $1`, version,
+	).Scan(&v.Version, &v.Reason, &by, &v.CreatedAt, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if by.Valid {
+		id := int(by.Int64)
+		v.CreatedBy = &id
+	}
+	v.Snapshot = &models.Taxonomy{}
+	if err := json.Unmarshal(raw, v.Snapshot); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// RollbackTaxonomy восстанавливает направления (иконки, сроки, фразы, порядок), группы и проблемы
+// из снимка одной транзакцией. Откат не выполняется, если пропадут категории, на которые ссылаются обращения.
+// Результат сохраняется новой версией с причиной rollback:N в той же транзакции.
+func RollbackTaxonomy(version int, actorID *int) (int, error) {
+	v, err := GetTaxonomyVersion(version)
+	if err != nil {
+		return 0, err
+	}
+	return ReplaceTaxonomy(v.Snapshot, actorID, fmt.Sprintf("rollback:%d", version))
+}
+
+// taxonomyPaths — ключи узлов tax в виде domain, domain/group, domain/group/issue.
+func taxonomyPaths(tax *models.Taxonomy) (domains, groups, issues []string) {
+	for _, d := range tax.Domains {
+		domains = append(domains, d.Key)
+		for _, g := range d.Groups {
+			groups = append(groups, d.Key+"/"+g.Key)
+			for _, is := range g.Issues {
+				issues = append(issues, d.Key+"/"+g.Key+"/"+is.Key)
+			}
+		}
+	}
+	return
+}
+
+// markersOrphanedByTaxonomy — сколько обращений ссылается на существующие категории, которых нет в tax.
+func markersOrphanedByTaxonomy(q dbExecutor, tax *models.Taxonomy) (map[string]int, error) {
+	domains, groups, issues := taxonomyPaths(tax)
+	rows, err := q.Query(`
+		SELECT m.domain_key, COUNT(*)::int FROM markers m
+		JOIN classification_domains c ON c.domain_key = m.domain_key
+		WHERE NOT (m.domain_key = ANY($1))
+		GROUP BY 1
+		UNION ALL
+		SELECT m.domain_key || '/' || m.group_key, COUNT(*)::int FROM markers m
+		JOIN classification_groups g ON g.domain_key = m.domain_key AND g.group_key = m.group_key
+		WHERE NOT (m.domain_key || '/' || m.group_key = ANY($2))
+		GROUP BY 1
+		UNION ALL
+		SELECT m.domain_key || '/' || m.group_key || '/' || m.issue_key, COUNT(*)::int FROM markers m
+		JOIN classification_issues i ON i.domain_key = m.domain_key AND i.group_key = m.group_key AND i.issue_key = m.issue_key
+		WHERE NOT (m.domain_key || '/' || m.group_key || '/' || m.issue_key = ANY($3))
+		GROUP BY 1`,
+		stringArray(domains), stringArray(groups), stringArray(issues))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var k string
+		var n int
+		if rows.Scan(&k, &n) == nil {
+			out[k] = n
+		}
+	}
+	return out, rows.Err()
+}
+
+// applyTaxonomyTx приводит таблицы таксономии к tax. Ключи, которых нет в tax, удаляются —
+// только если на них не ссылаются обращения.
+func applyTaxonomyTx(tx *sql.Tx, tax *models.Taxonomy) error {
+	domains, groups, issues := taxonomyPaths(tax)
+	orphans, err := markersOrphanedByTaxonomy(tx, tax)
+	if err != nil {
+		return err
+	}
+	if len(orphans) > 0 {
+		keys := make([]string, 0, len(orphans))
+		for k := range orphans {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("Нельзя применить: есть обращения с удаляемыми категориями: %s", strings.Join(keys, ", "))
+	}
+
+	if _, err := tx.Exec(`DELETE FROM classification_domains WHERE NOT (domain_key = ANY($1))`, stringArray(domains)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM classification_groups WHERE NOT (domain_key || '/' || group_key = ANY($1))`, stringArray(groups)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM classification_issues
+		WHERE NOT (domain_key || '/' || group_key || '/' || issue_key = ANY($1))`, stringArray(issues)); err != nil {
+		return err
+	}
+	for i, d := range tax.Domains {
+		resDays := d.ResolutionDays
+		if resDays < 1 {
+			resDays = DefaultResolutionDays
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO classification_domains (domain_key, label_ru, marker_icon, training_phrases, resolution_days, sort_order)
+			VALUES ($1, $2, $3, $4::jsonb, $5, $6)
+			ON CONFLICT (domain_key) DO UPDATE SET label_ru = EXCLUDED.label_ru, marker_icon = EXCLUDED.marker_icon,
+				training_phrases = EXCLUDED.training_phrases, resolution_days = EXCLUDED.resolution_days,
+				sort_order = EXCLUDED.sort_order`,
+			d.Key, d.LabelRu, NormalizeMarkerIcon(d.MarkerIcon), phrasesJSON(d.TrainingPhrasesRu), resDays, i,
+		); err != nil {
+			return err
+		}
+		for gi, g := range d.Groups {
+			if _, err := tx.Exec(`
+				INSERT INTO classification_groups (domain_key, group_key, label_ru, training_phrases, sort_order)
+				VALUES ($1, $2, $3, $4::jsonb, $5)
+				ON CONFLICT (domain_key, group_key) DO UPDATE SET label_ru = EXCLUDED.label_ru,
+					training_phrases = EXCLUDED.training_phrases, sort_order = EXCLUDED.sort_order`,
+				d.Key, g.Key, g.LabelRu, phrasesJSON(g.TrainingPhrasesRu), gi,
+			); err != nil {
+				return err
+			}
+			for ii, is := range g.Issues {
+				if _, err := tx.Exec(`
+					INSERT INTO classification_issues (domain_key, group_key, issue_key, label_ru, training_phrases, sort_order)
+					VALUES ($1, $2, $3, $4, $5::jsonb, $6)
+					ON CONFLICT (domain_key, group_key, issue_key) DO UPDATE SET label_ru = EXCLUDED.label_ru,
+						training_phrases = EXCLUDED.training_phrases, sort_order = EXCLUDED.sort_order`,
+					d.Key, g.Key, is.Key, is.LabelRu, phrasesJSON(is.TrainingPhrasesRu), ii,
+				); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func phrasesJSON(phrases []string) string {
+	if phrases == nil {
+		return "[]"
+	}
+	raw, _ := json.Marshal(phrases)
+	return string(raw)
+}
+
+func stringArray(s []string) interface{} {
+	if s == nil {
+		s = []string{}
+	}
+	return pq.Array(s)
+}
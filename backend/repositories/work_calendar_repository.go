@@ -0,0 +1,333 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/database"
+	"backend/utils"
+
+	"github.com/lib/pq"
+)
+
+var ErrCalendarDayExists = errors.New("calendar day already exists")
+
+// workCalendarTTL — сколько календарь живёт в кэше. Правки через админку сбрасывают кэш сразу,
+// TTL нужен для правок, сделанных на другом экземпляре.
+const workCalendarTTL = 5 * time.Minute
+
+type cachedWorkCalendar struct {
+	cal      *utils.WorkCalendar
+	loadedAt time.Time
+}
+
+// workCalendarCache — календари по ведомству (0 — общий). Календарь после загрузки только читается.
+var workCalendarCache = struct {
+	sync.Mutex
+	byDept map[int]cachedWorkCalendar
+}{byDept: map[int]cachedWorkCalendar{}}
+
+// invalidateWorkCalendarCache — календарь изменили, следующий LoadWorkCalendar читает БД.
+func invalidateWorkCalendarCache() {
+	workCalendarCache.Lock()
+	workCalendarCache.byDept = map[int]cachedWorkCalendar{}
+	workCalendarCache.Unlock()
+}
+
+// WorkCalendarDay — праздник (is_workday=false) или перенесённый рабочий день; department_id NULL — для всего города.
+type WorkCalendarDay struct {
+	ID           int       `json:"id"`
+	Day          string    `json:"day"`
+	DepartmentID *int      `json:"department_id,omitempty"`
+	IsWorkday    bool      `json:"is_workday"`
+	NameRu       string    `json:"name_ru"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ListWorkCalendarDays — дни календаря за год (year <= 0 — все).
+func ListWorkCalendarDays(year int) ([]WorkCalendarDay, error) {
+	q := `SELECT id, day, department_id, is_workday, name_ru, created_at FROM work_calendar_days`
+	args := []interface{}{}
+	if year > 0 {
+		q += ` WHERE EXTRACT(YEAR FROM day) = $1`
+		args = append(args, year)
+	}
+	rows, err := database.DB.Query(q+` ORDER BY day ASC, department_id NULLS FIRST`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []WorkCalendarDay
+	for rows.Next() {
+		var d WorkCalendarDay
+		var day time.Time
+		var dept sql.NullInt64
+		if rows.Scan(&d.ID, &day, &dept, &d.IsWorkday, &d.NameRu, &d.CreatedAt) != nil {
+			continue
+		}
+		d.Day = day.Format("2006-01-02")
+		if dept.Valid {
+			v := int(dept.Int64)
+			d.DepartmentID = &v
+		}
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+func CreateWorkCalendarDay(day time.Time, departmentID *int, isWorkday bool, nameRu string, actorID int) (int, error) {
+	var dept interface{}
+	if departmentID != nil && *departmentID > 0 {
+		dept = *departmentID
+	}
+	var id int
+	err := database.DB.QueryRow(`
+		INSERT INTO work_calendar_days (day, department_id, is_workday, name_ru, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+		RETURNING id`,
+		day.Format("2006-01-02"), dept, isWorkday, strings.TrimSpace(nameRu), actorID,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrCalendarDayExists
+	}
+	if err == nil {
+		invalidateWorkCalendarCache()
+	}
+	return id, err
+}
+
+func DeleteWorkCalendarDay(id int) error {
+	res, err := database.DB.Exec(`DELETE FROM work_calendar_days WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	invalidateWorkCalendarCache()
+	return nil
+}
+
+// SetDepartmentWeekendDays — выходные ведомства в ISO-днях (1 = пн … 7 = вс); пустой список — общий календарь.
+func SetDepartmentWeekendDays(departmentID int, isoDays []int) error {
+	var val interface{}
+	if len(isoDays) > 0 {
+		seen := map[int]bool{}
+		days := make([]int64, 0, len(isoDays))
+		for _, d := range isoDays {
+			if _, ok := utils.ISOWeekday(d); !ok {
+				return fmt.Errorf("invalid weekday %d (expected 1..7)", d)
+			}
+			if !seen[d] {
+				seen[d] = true
+				days = append(days, int64(d))
+			}
+		}
+		if len(days) == 7 {
+			return errors.New("department must have at least one working weekday")
+		}
+		val = pq.Array(days)
+	}
+	res, err := database.DB.Exec(`UPDATE departments SET weekend_days = $1 WHERE id = $2`, val, departmentID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	invalidateWorkCalendarCache()
+	return nil
+}
+
+// LoadWorkCalendar — общий календарь с наложением дней и выходных ведомства (departmentID <= 0 — только общий).
+// При ошибке БД возвращает календарь «пн–пт», чтобы сроки всё равно считались.
+// Результат кэшируется (см. workCalendarTTL): срок считается на каждую метку, а календарь меняется редко.
+func LoadWorkCalendar(departmentID int) *utils.WorkCalendar {
+	if departmentID < 0 {
+		departmentID = 0
+	}
+	workCalendarCache.Lock()
+	c, ok := workCalendarCache.byDept[departmentID]
+	workCalendarCache.Unlock()
+	if ok && time.Since(c.loadedAt) < workCalendarTTL {
+		return c.cal
+	}
+	cal, err := loadWorkCalendar(departmentID)
+	if err != nil {
+		// не кэшируем: при ошибке БД это только выходные, без праздников
+		return cal
+	}
+	workCalendarCache.Lock()
+	workCalendarCache.byDept[departmentID] = cachedWorkCalendar{cal: cal, loadedAt: time.Now()}
+	workCalendarCache.Unlock()
+	return cal
+}
+
+func loadWorkCalendar(departmentID int) (*utils.WorkCalendar, error) {
+	var weekend []time.Weekday
+	var loadErr error
+	if departmentID > 0 {
+		var days []int64
+		err := database.DB.QueryRow(
+			`SELECT weekend_days FROM departments WHERE id = $1`, departmentID,
+		).Scan(pq.Array(&days))
+		if err != nil && err != sql.ErrNoRows {
+			loadErr = err
+		}
+		if err == nil {
+			for _, d := range days {
+				if wd, ok := utils.ISOWeekday(int(d)); ok {
+					weekend = append(weekend, wd)
+				}
+			}
+		}
+	}
+	cal := utils.NewWorkCalendar(weekend)
+
+	// дни ведомства идут последними и перекрывают общие
+	rows, err := database.DB.Query(`
+		SELECT day, is_workday FROM work_calendar_days
+		WHERE department_id IS NULL OR department_id = $1
+		ORDER BY department_id NULLS FIRST, day`, departmentID)
+	if err != nil {
+		return cal, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day time.Time
+		var isWorkday bool
+		if rows.Scan(&day, &isWorkday) == nil {
+			cal.SetDay(day, isWorkday)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return cal, err
+	}
+	return cal, loadErr
+}
+
+// RecomputeOpenSLADueDates пересчитывает сроки незакрытых меток от сохранённой точки отсчёта
+// после изменения календаря. Возвращает число меток, у которых срок сдвинулся.
+func RecomputeOpenSLADueDates(actorID *int) (int, error) {
+	return recomputeSLADueDates(actorID, 0)
+}
+
+// recomputeSLADueDates — пересчёт для всех открытых меток (markerID <= 0) или одной метки.
+func recomputeSLADueDates(actorID *int, markerID int) (int, error) {
+	var ids []int
+	if markerID > 0 {
+		ids = []int{markerID}
+	}
+	return recomputeSLADueDatesWith(database.DB, actorID, ids)
+}
+
+// recomputeSLADueDatesWith — пересчёт для меток ids (nil — все открытые) одним проходом на q (в т.ч. в транзакции):
+// дни решения направления и ведомство читаются тем же запросом, календари — из кэша, сроки пишутся одним UPDATE.
+func recomputeSLADueDatesWith(q dbExecutor, actorID *int, ids []int) (int, error) {
+	var idFilter interface{}
+	if ids != nil {
+		if len(ids) == 0 {
+			return 0, nil
+		}
+		idFilter = int64Array(ids)
+	}
+	rows, err := q.Query(`
+		SELECT m.id, LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')), COALESCE(m.domain_key, ''),
+		       COALESCE(m.department_id, `+departmentForDomainSQL("m.domain_key")+`, 0),
+		       cd.resolution_days,
+		       m.response_sla_from, m.response_due_at, m.resolution_sla_from, m.resolution_due_at,
+		       m.sla_paused_seconds
+		FROM markers m
+		LEFT JOIN classification_domains cd ON cd.domain_key = m.domain_key
+		WHERE ($1::bigint[] IS NULL OR m.id = ANY($1))
+		  AND ((LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) = 'pending' AND m.response_sla_from IS NOT NULL)
+		   OR (LOWER(COALESCE(NULLIF(TRIM(m.status), ''), 'pending')) IN ('approved', 'in_progress', 'reopened')
+		       AND m.resolution_sla_from IS NOT NULL))`, idFilter)
+	if err != nil {
+		return 0, err
+	}
+	type dueChange struct {
+		id       int
+		field    string
+		old, new time.Time
+		hadOld   bool
+	}
+	var changes []dueChange
+	general := LoadWorkCalendar(0)
+	deptCals := map[int]*utils.WorkCalendar{}
+	for rows.Next() {
+		var id, deptID int
+		var status, domainKey string
+		var days sql.NullInt64
+		var respFrom, respDue, resFrom, resDue sql.NullTime
+		var pausedSeconds int64
+		if err := rows.Scan(&id, &status, &domainKey, &deptID, &days, &respFrom, &respDue, &resFrom, &resDue, &pausedSeconds); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if status == "pending" {
+			due := general.AddWorkdays(respFrom.Time, DefaultResponseDays)
+			if !respDue.Valid || !respDue.Time.Equal(due) {
+				changes = append(changes, dueChange{id, "response_due_at", respDue.Time, due, respDue.Valid})
+			}
+			continue
+		}
+		cal, ok := deptCals[deptID]
+		if !ok {
+			cal = LoadWorkCalendar(deptID)
+			deptCals[deptID] = cal
+		}
+		// завершённые паузы ожидания заявителя продлевают срок (см. ResumeMarkerSLA)
+		due := cal.AddWorkdays(resFrom.Time, resolutionDays(domainKey, days)).Add(time.Duration(pausedSeconds) * time.Second)
+		if !resDue.Valid || !resDue.Time.Equal(due) {
+			changes = append(changes, dueChange{id, "resolution_due_at", resDue.Time, due, resDue.Valid})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	// у метки меняется только один из сроков; второй в массиве — NULL и остаётся прежним
+	changedIDs := make([]int, len(changes))
+	respDues := make([]*time.Time, len(changes))
+	resDues := make([]*time.Time, len(changes))
+	logged := make([]markerChange, len(changes))
+	for i, c := range changes {
+		due := c.new
+		changedIDs[i] = c.id
+		if c.field == "response_due_at" {
+			respDues[i] = &due
+		} else {
+			resDues[i] = &due
+		}
+		oldVal := ""
+		if c.hadOld {
+			oldVal = c.old.Format(time.RFC3339)
+		}
+		logged[i] = markerChange{c.id, c.field, oldVal, c.new.Format(time.RFC3339)}
+	}
+	if _, err := q.Exec(`
+		UPDATE markers m SET
+			response_due_at = COALESCE(c.response_due_at, m.response_due_at),
+			resolution_due_at = COALESCE(c.resolution_due_at, m.resolution_due_at)
+		FROM unnest($1::bigint[], $2::timestamp[], $3::timestamp[]) AS c(id, response_due_at, resolution_due_at)
+		WHERE m.id = c.id`,
+		int64Array(changedIDs), pq.Array(respDues), pq.Array(resDues),
+	); err != nil {
+		return 0, err
+	}
+	if err := insertMarkerChanges(q, logged, actorID); err != nil {
+		return 0, err
+	}
+	return len(changes), nil
+}
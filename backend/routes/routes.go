@@ -46,7 +46,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", allow)
 		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Accept, Origin")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Accept, Origin, Last-Event-ID")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
@@ -98,15 +98,34 @@ func SetupRoutes(r *mux.Router) {
 	r.Handle("/api/moderation/stats", middleware.JWTMiddleware(http.HandlerFunc(handlers.ModerationStatsHandler))).Methods("GET", "OPTIONS")
 	r.Handle("/api/moderation/markers", middleware.JWTMiddleware(http.HandlerFunc(handlers.ListModerationMarkersHandler))).Methods("GET", "OPTIONS")
 	r.Handle("/api/moderation/markers/bulk-status", middleware.JWTMiddleware(http.HandlerFunc(handlers.BulkUpdateMarkerStatusHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/moderation/markers/{id}/review", middleware.JWTMiddleware(http.HandlerFunc(handlers.MarkerReviewersHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/moderation/markers/{id}/review", middleware.JWTMiddleware(http.HandlerFunc(handlers.StartMarkerReviewHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/moderation/markers/{id}/review", middleware.JWTMiddleware(http.HandlerFunc(handlers.ReleaseMarkerReviewHandler))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/moderation/markers/{id}/merge", middleware.JWTMiddleware(http.HandlerFunc(handlers.MergeMarkersHandler))).Methods("POST", "OPTIONS")
 	r.Handle("/api/moderation/abuse-reports", middleware.JWTMiddleware(http.HandlerFunc(handlers.ListModerationAbuseReportsHandler))).Methods("GET", "OPTIONS")
 	r.Handle("/api/moderation/abuse-reports/{id}", middleware.JWTMiddleware(http.HandlerFunc(handlers.PatchModerationAbuseReportHandler))).Methods("PATCH", "OPTIONS")
 
 	r.HandleFunc("/api/markers", handlers.GetMarkersHandler).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/markers/clusters", handlers.GetMarkerClustersHandler).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/tiles/{z:[0-9]+}/{x:[0-9]+}/{y:[0-9]+}.mvt", handlers.MarkerTileHandler).Methods("GET", "OPTIONS")
 	r.Handle("/api/markers", middleware.JWTMiddleware(http.HandlerFunc(handlers.CreateMarkerHandler))).Methods("POST", "OPTIONS")
 	r.Handle("/api/markers/{id}", middleware.JWTMiddleware(http.HandlerFunc(handlers.DeleteMarkerHandler))).Methods("DELETE", "OPTIONS")
 	r.Handle("/api/markers/{id}", middleware.JWTMiddleware(http.HandlerFunc(handlers.PatchMarkerHandler))).Methods("PATCH", "OPTIONS")
 	r.HandleFunc("/api/markers/{id}/status-history", handlers.GetMarkerStatusHistoryHandler).Methods("GET", "OPTIONS")
 	r.Handle("/api/markers/{id}/status", middleware.JWTMiddleware(http.HandlerFunc(handlers.UpdateMarkerStatusHandler))).Methods("PATCH", "OPTIONS")
+	r.Handle("/api/markers/{id}/transitions", middleware.JWTMiddleware(http.HandlerFunc(handlers.MarkerTransitionsHandler))).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/markers/{id}/reopen-requests", handlers.ListReopenRequestsHandler).Methods("GET", "OPTIONS")
+	r.Handle("/api/markers/{id}/reopen-requests", middleware.JWTMiddleware(http.HandlerFunc(handlers.PostReopenRequestHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/markers/{id}/department", middleware.JWTMiddleware(http.HandlerFunc(handlers.PutMarkerDepartmentHandler))).Methods("PUT", "OPTIONS")
+	r.Handle("/api/markers/{id}/classification", middleware.JWTMiddleware(http.HandlerFunc(handlers.PutMarkerClassificationHandler))).Methods("PUT", "OPTIONS")
+	r.Handle("/api/markers/{id}/classification/confirm", middleware.JWTMiddleware(http.HandlerFunc(handlers.ConfirmMarkerClassificationHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/department/markers", middleware.JWTMiddleware(http.HandlerFunc(handlers.DepartmentQueueHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/department/markers/{id}/status", middleware.JWTMiddleware(http.HandlerFunc(handlers.DepartmentUpdateMarkerStatusHandler))).Methods("PATCH", "OPTIONS")
+	r.Handle("/api/department/markers/{id}/after-photo", middleware.JWTMiddleware(http.HandlerFunc(handlers.DepartmentAfterPhotoHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/department/stats", middleware.JWTMiddleware(http.HandlerFunc(handlers.DepartmentStatsHandler))).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/markers/{id}/official-responses", handlers.ListOfficialResponsesHandler).Methods("GET", "OPTIONS")
+	r.Handle("/api/markers/{id}/official-responses", middleware.JWTMiddleware(http.HandlerFunc(handlers.PostOfficialResponseHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/markers/{id}/official-responses/{responseId:[0-9]+}", middleware.JWTMiddleware(http.HandlerFunc(handlers.PutOfficialResponseEntryHandler))).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/api/markers/{id}/official-response", handlers.GetOfficialResponseHandler).Methods("GET", "OPTIONS")
 	r.Handle("/api/markers/{id}/official-response", middleware.JWTMiddleware(http.HandlerFunc(handlers.PostOfficialResponseHandler))).Methods("POST", "OPTIONS")
 	r.Handle("/api/markers/{id}/official-response", middleware.JWTMiddleware(http.HandlerFunc(handlers.PutOfficialResponseHandler))).Methods("PUT", "OPTIONS")
@@ -129,6 +148,7 @@ func SetupRoutes(r *mux.Router) {
 	r.HandleFunc("/api/analytics/dashboard", handlers.AnalyticsDashboardHandler).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/realtime/stats", handlers.RealtimeStatsHandler).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/ws", handlers.WebSocketHandler)
+	r.HandleFunc("/api/events", handlers.EventsStreamHandler).Methods("GET", "OPTIONS")
 
 	r.HandleFunc("/api/markers/{id}/timeline", handlers.MarkerTimelineHandler).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/users/{id}/public", handlers.PublicUserProfileHandler).Methods("GET", "OPTIONS")
@@ -141,6 +161,11 @@ func SetupRoutes(r *mux.Router) {
 	r.Handle("/api/favorites/{markerId}/status", middleware.JWTMiddleware(http.HandlerFunc(handlers.FavoriteStatusHandler))).Methods("GET", "OPTIONS")
 
 	r.Handle("/api/abuse-reports", middleware.JWTMiddleware(http.HandlerFunc(handlers.PostAbuseReportHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/calendar", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminListWorkCalendarHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/calendar/days", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminCreateWorkCalendarDayHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/calendar/days/{id:[0-9]+}", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminDeleteWorkCalendarDayHandler))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/admin/calendar/recompute", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminRecomputeSLAHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/departments/{id:[0-9]+}/weekend-days", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminPutDepartmentWeekendHandler))).Methods("PUT", "OPTIONS")
 	r.Handle("/api/admin/audit-log", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminAuditLogHandler))).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/markers/nearby", handlers.NearbyMarkersHandler).Methods("GET", "OPTIONS")
 
@@ -149,10 +174,31 @@ func SetupRoutes(r *mux.Router) {
 	r.Handle("/api/markers/{id}/supports", middleware.JWTMiddleware(http.HandlerFunc(handlers.DeleteMarkerSupportHandler))).Methods("DELETE", "OPTIONS")
 
 	r.Handle("/api/admin/classifications", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminListClassificationsHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/classification-quality", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminClassificationQualityHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/taxonomy/versions", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminListTaxonomyVersionsHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/taxonomy/versions/{version:[0-9]+}", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminGetTaxonomyVersionHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/taxonomy/versions/{version:[0-9]+}/rollback", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminRollbackTaxonomyHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/taxonomy/diff", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminDiffTaxonomyVersionsHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/moderation/reclassify", middleware.JWTMiddleware(http.HandlerFunc(handlers.EnqueueReclassificationHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/moderation/reclassify/status", middleware.JWTMiddleware(http.HandlerFunc(handlers.ReclassificationStatusHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/moderation/reclassify/suggestions", middleware.JWTMiddleware(http.HandlerFunc(handlers.ListReclassificationSuggestionsHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/moderation/reclassify/accept", middleware.JWTMiddleware(http.HandlerFunc(handlers.AcceptReclassificationHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/moderation/reclassify/dismiss", middleware.JWTMiddleware(http.HandlerFunc(handlers.DismissReclassificationHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/classification-labels/export", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminExportClassificationLabelsHandler))).Methods("GET", "OPTIONS")
 	r.Handle("/api/admin/classifications", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminCreateClassificationHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/classifications/import", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminImportTaxonomyHandler))).Methods("POST", "OPTIONS")
 	r.Handle("/api/admin/classifications/reorder", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminReorderClassificationsHandler))).Methods("POST", "OPTIONS")
 	r.Handle("/api/admin/classifications/{key}", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminPatchClassificationHandler))).Methods("PATCH", "OPTIONS")
 	r.Handle("/api/admin/classifications/{key}", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminDeleteClassificationHandler))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminListTaxonomyGroupsHandler))).Methods("GET", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminCreateTaxonomyGroupHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups/reorder", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminReorderTaxonomyGroupsHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups/{group}", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminPatchTaxonomyGroupHandler))).Methods("PATCH", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups/{group}", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminDeleteTaxonomyGroupHandler))).Methods("DELETE", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups/{group}/issues", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminCreateTaxonomyIssueHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups/{group}/issues/reorder", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminReorderTaxonomyIssuesHandler))).Methods("POST", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups/{group}/issues/{issue}", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminPatchTaxonomyIssueHandler))).Methods("PATCH", "OPTIONS")
+	r.Handle("/api/admin/classifications/{key}/groups/{group}/issues/{issue}", middleware.JWTMiddleware(http.HandlerFunc(handlers.AdminDeleteTaxonomyIssueHandler))).Methods("DELETE", "OPTIONS")
 
 	r.HandleFunc("/api/polls", handlers.ListPollsHandler).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/polls/active-widget", handlers.ActivePollWidgetHandler).Methods("GET", "OPTIONS")
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"backend/classifier"
+	"backend/models"
+	"backend/repositories"
+)
+
+const classifierTopK = 3
+
+// AISourceUnavailable — классификатор не ответил, категория и уверенность клиента не подтверждены.
+const AISourceUnavailable = "unavailable"
+
+var (
+	classifierOnce   sync.Once
+	classifierClient *classifier.Client
+)
+
+func markerClassifier() *classifier.Client {
+	classifierOnce.Do(func() { classifierClient = classifier.FromEnv() })
+	return classifierClient
+}
+
+// SetMarkerClassifier подменяет клиент (тесты, альтернативный адрес).
+func SetMarkerClassifier(c *classifier.Client) {
+	classifierOnce.Do(func() {})
+	classifierClient = c
+}
+
+// classifyMarker — по тексту, а если есть фото — и по снимку; побеждает более уверенный ответ.
+func classifyMarker(ctx context.Context, text, imageURL string) (*classifier.Result, error) {
+	c := markerClassifier()
+	var best *classifier.Result
+	var lastErr error
+	if strings.TrimSpace(text) != "" {
+		res, err := c.ClassifyText(ctx, text, classifierTopK)
+		if err != nil {
+			lastErr = err
+		} else if res.Confidence() > 0 {
+			best = res
+		}
+	}
+	if path := localUploadPath(imageURL); path != "" && !errors.Is(lastErr, classifier.ErrUnavailable) {
+		if f, err := os.Open(path); err == nil {
+			res, err := c.ClassifyImage(ctx, path, f, classifierTopK)
+			f.Close()
+			if err != nil {
+				lastErr = err
+			} else if res.Confidence() > best.Confidence() {
+				best = res
+			}
+		}
+	}
+	if best == nil {
+		return nil, lastErr
+	}
+	return best, nil
+}
+
+// localUploadPath — файл в ./uploads для image_url вида /uploads/<name>.
+func localUploadPath(imageURL string) string {
+	if !strings.HasPrefix(imageURL, "/uploads/") {
+		return ""
+	}
+	name := filepath.Base(imageURL)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return filepath.Join("uploads", name)
+}
+
+// ClassifyMarkerRequest заменяет присланные клиентом ai_confidence и ai_* серверным результатом.
+// Выбор направления пользователем сохраняется; без него берётся направление классификатора.
+// Возвращает расхождение клиента с сервером (nil — совпали или сравнивать не с чем).
+func ClassifyMarkerRequest(ctx context.Context, req *models.CreateMarkerRequest) *repositories.ClassificationDisagreement {
+	clientDomain := strings.TrimSpace(req.DomainKey)
+	clientIssue := strings.TrimSpace(req.IssueKey)
+	clientConf := req.AIConfidence
+
+	res, err := classifyMarker(ctx, req.Text, req.ImageURL)
+	req.AIConfidence = nil
+	if err != nil || res == nil || res.Best == nil {
+		if err != nil {
+			log.Printf("classifier: %v", err)
+		}
+		req.AISource = AISourceUnavailable
+		return nil
+	}
+	conf := res.Confidence()
+	req.AIConfidence = &conf
+	req.AIDomainKey = res.Best.DomainKey
+	req.AIGroupKey = res.Best.GroupKey
+	req.AIIssueKey = res.Best.IssueKey
+	req.AISource = res.Source
+	if req.AISource == "" {
+		req.AISource = "text"
+	}
+	if clientDomain == "" {
+		req.DomainKey, req.GroupKey, req.IssueKey = res.Best.DomainKey, res.Best.GroupKey, res.Best.IssueKey
+		if clientConf == nil {
+			return nil
+		}
+	}
+
+	disagrees := clientDomain != "" && clientDomain != res.Best.DomainKey ||
+		clientIssue != "" && res.Best.IssueKey != "" && clientIssue != res.Best.IssueKey ||
+		clientConf != nil && math.Abs(*clientConf-conf) > 0.1
+	if !disagrees {
+		return nil
+	}
+	return &repositories.ClassificationDisagreement{
+		ClientDomainKey:  clientDomain,
+		ClientIssueKey:   clientIssue,
+		ClientConfidence: clientConf,
+		ServerDomainKey:  res.Best.DomainKey,
+		ServerIssueKey:   res.Best.IssueKey,
+		ServerConfidence: &conf,
+		ServerSource:     req.AISource,
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/classifier"
+	"backend/models"
+)
+
+// withTestClassifier — классификатор, который на любой текст отвечает roads.surface.pothole с уверенностью 0.81.
+func withTestClassifier(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/classify" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, `{"best":{"leaf":"roads.surface.pothole","score":0.81,"domain_key":"roads",
+			"group_key":"surface","issue_key":"pothole"},"ai_confidence":0.81,"source":"text"}`)
+	}))
+	prev := markerClassifier()
+	SetMarkerClassifier(classifier.NewClient(srv.URL, time.Second))
+	t.Cleanup(func() {
+		SetMarkerClassifier(prev)
+		srv.Close()
+	})
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestClassifyMarkerRequestDisagreement(t *testing.T) {
+	withTestClassifier(t)
+
+	cases := []struct {
+		name        string
+		req         models.CreateMarkerRequest
+		wantDomain  string
+		wantIssue   string
+		disagreeing bool
+	}{
+		{
+			name:       "no user choice takes classifier category",
+			req:        models.CreateMarkerRequest{Text: "яма"},
+			wantDomain: "roads", wantIssue: "pothole",
+		},
+		{
+			name:       "same domain and close confidence",
+			req:        models.CreateMarkerRequest{Text: "яма", DomainKey: "roads", IssueKey: "pothole", AIConfidence: floatPtr(0.85)},
+			wantDomain: "roads", wantIssue: "pothole",
+		},
+		{
+			name:       "user picked another domain",
+			req:        models.CreateMarkerRequest{Text: "яма", DomainKey: "utilities", IssueKey: "light_off"},
+			wantDomain: "utilities", wantIssue: "light_off", disagreeing: true,
+		},
+		{
+			name:       "same domain, another issue",
+			req:        models.CreateMarkerRequest{Text: "яма", DomainKey: "roads", IssueKey: "curb"},
+			wantDomain: "roads", wantIssue: "curb", disagreeing: true,
+		},
+		{
+			name:       "client confidence was forged",
+			req:        models.CreateMarkerRequest{Text: "яма", AIConfidence: floatPtr(0.99)},
+			wantDomain: "roads", wantIssue: "pothole", disagreeing: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.req
+			d := ClassifyMarkerRequest(context.Background(), &req)
+			if req.DomainKey != tc.wantDomain || req.IssueKey != tc.wantIssue {
+				t.Fatalf("category = %s/%s, want %s/%s", req.DomainKey, req.IssueKey, tc.wantDomain, tc.wantIssue)
+			}
+			// ai_* и уверенность всегда серверные, что бы ни прислал клиент
+			if req.AIDomainKey != "roads" || req.AIIssueKey != "pothole" || req.AISource != "text" {
+				t.Fatalf("ai fields = %s/%s source %q", req.AIDomainKey, req.AIIssueKey, req.AISource)
+			}
+			if req.AIConfidence == nil || *req.AIConfidence != 0.81 {
+				t.Fatalf("ai_confidence = %v, want 0.81", req.AIConfidence)
+			}
+			if (d != nil) != tc.disagreeing {
+				t.Fatalf("disagreement = %+v, want %v", d, tc.disagreeing)
+			}
+			if d != nil {
+				if d.ClientDomainKey != tc.req.DomainKey || d.ServerDomainKey != "roads" || d.ServerIssueKey != "pothole" {
+					t.Fatalf("unexpected disagreement %+v", d)
+				}
+				if d.ServerConfidence == nil || *d.ServerConfidence != 0.81 {
+					t.Fatalf("server confidence = %v", d.ServerConfidence)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyMarkerRequestClassifierDown(t *testing.T) {
+	prev := markerClassifier()
+	SetMarkerClassifier(classifier.NewClient("", time.Second))
+	t.Cleanup(func() { SetMarkerClassifier(prev) })
+
+	req := models.CreateMarkerRequest{Text: "яма", DomainKey: "roads", AIConfidence: floatPtr(0.99)}
+	if d := ClassifyMarkerRequest(context.Background(), &req); d != nil {
+		t.Fatalf("unexpected disagreement %+v", d)
+	}
+	if req.AIConfidence != nil || req.AISource != AISourceUnavailable {
+		t.Fatalf("client confidence must be dropped, got %v source %q", req.AIConfidence, req.AISource)
+	}
+	if req.DomainKey != "roads" {
+		t.Fatalf("user choice lost: %q", req.DomainKey)
+	}
+}
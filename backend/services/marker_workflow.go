@@ -0,0 +1,187 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Статусы обращения.
+const (
+	StatusPending    = "pending"
+	StatusApproved   = "approved"
+	StatusInProgress = "in_progress"
+	StatusResolved   = "resolved"
+	StatusRejected   = "rejected"
+	StatusReopened   = "reopened"
+	// StatusMerged — дубль, поглощённый другим обращением (markers.merged_into); переходов из него нет.
+	StatusMerged = "merged"
+)
+
+// Роли, которым разрешён переход.
+const (
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+	// RoleContest — житель, чьё оспаривание решения прошло проверку (владелец или N поддержавших в окне).
+	RoleContest = "contest"
+	// RoleDepartment — представитель ведомства, которому назначена метка (проверка области — в обработчике).
+	RoleDepartment = "department"
+)
+
+// StatusTransition — разрешённый переход статуса и его правила.
+type StatusTransition struct {
+	From              string   `json:"from"`
+	To                string   `json:"to"`
+	LabelRu           string   `json:"label_ru"`
+	RequireNote       bool     `json:"require_note"`
+	RequireImageAfter bool     `json:"require_image_after"`
+	Roles             []string `json:"roles"`
+}
+
+// markerWorkflow — единственное место, где описан жизненный цикл обращения.
+var markerWorkflow = []StatusTransition{
+	{From: StatusPending, To: StatusApproved, LabelRu: "Одобрить", Roles: []string{RoleModerator}},
+	{From: StatusPending, To: StatusRejected, LabelRu: "Отклонить", RequireNote: true, Roles: []string{RoleModerator}},
+	{From: StatusApproved, To: StatusInProgress, LabelRu: "Взять в работу", Roles: []string{RoleModerator, RoleDepartment}},
+	{From: StatusInProgress, To: StatusResolved, LabelRu: "Отметить решённым", RequireImageAfter: true, Roles: []string{RoleModerator, RoleDepartment}},
+	{From: StatusResolved, To: StatusInProgress, LabelRu: "Переоткрыть", RequireNote: true, Roles: []string{RoleModerator, RoleDepartment}},
+	{From: StatusRejected, To: StatusPending, LabelRu: "Вернуть на проверку", RequireNote: true, Roles: []string{RoleAdmin}},
+	{From: StatusResolved, To: StatusReopened, LabelRu: "Оспорить решение", RequireNote: true, Roles: []string{RoleContest}},
+	{From: StatusReopened, To: StatusInProgress, LabelRu: "Взять в работу повторно", Roles: []string{RoleModerator, RoleDepartment}},
+	{From: StatusReopened, To: StatusResolved, LabelRu: "Отметить решённым", RequireImageAfter: true, Roles: []string{RoleModerator, RoleDepartment}},
+}
+
+// WorkflowActor — кто меняет статус.
+type WorkflowActor struct {
+	UserID          int
+	IsModerator     bool
+	IsAdmin         bool
+	ContestVerified bool
+	// DepartmentRep — представитель ведомства, назначенного метке.
+	DepartmentRep bool
+	// ForceReviewLock — сменить статус, хотя метку сейчас проверяет другой модератор.
+	ForceReviewLock bool
+}
+
+// HasRole — admin включает права модератора.
+func (a WorkflowActor) HasRole(role string) bool {
+	switch role {
+	case RoleModerator:
+		return a.IsModerator || a.IsAdmin
+	case RoleAdmin:
+		return a.IsAdmin
+	case RoleContest:
+		return a.ContestVerified
+	case RoleDepartment:
+		return a.DepartmentRep
+	}
+	return false
+}
+
+func (a WorkflowActor) allowed(t StatusTransition) bool {
+	for _, role := range t.Roles {
+		if a.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkflowError — нарушение правил перехода; Code стабилен для фронтенда.
+type WorkflowError struct {
+	Code    string
+	Message string
+}
+
+func (e *WorkflowError) Error() string {
+	return e.Message
+}
+
+// HTTPStatus — код ответа для нарушения.
+func (e *WorkflowError) HTTPStatus() int {
+	switch e.Code {
+	case "forbidden":
+		return http.StatusForbidden
+	case "transition_not_allowed", "marker_locked", "status_conflict":
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// NormalizeMarkerStatus — нижний регистр, пустой статус = pending.
+func NormalizeMarkerStatus(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return StatusPending
+	}
+	return s
+}
+
+// IsKnownMarkerStatus — статус встречается в схеме переходов.
+func IsKnownMarkerStatus(s string) bool {
+	for _, t := range markerWorkflow {
+		if t.From == s || t.To == s {
+			return true
+		}
+	}
+	return false
+}
+
+// KnownMarkerStatuses — все статусы схемы в порядке первого появления.
+func KnownMarkerStatuses() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range markerWorkflow {
+		for _, s := range []string{t.From, t.To} {
+			if !seen[s] {
+				seen[s] = true
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// FindTransition — правило перехода from → to.
+func FindTransition(from, to string) (StatusTransition, bool) {
+	from, to = NormalizeMarkerStatus(from), NormalizeMarkerStatus(to)
+	for _, t := range markerWorkflow {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return StatusTransition{}, false
+}
+
+// AvailableTransitions — переходы из статуса, доступные актору (для кнопок в UI).
+func AvailableTransitions(from string, actor WorkflowActor) []StatusTransition {
+	from = NormalizeMarkerStatus(from)
+	out := []StatusTransition{}
+	for _, t := range markerWorkflow {
+		if t.From == from && actor.allowed(t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// CheckTransition проверяет переход и его правила; nil — можно применять.
+func CheckTransition(from, to string, actor WorkflowActor, note string, hasImageAfter bool) error {
+	t, ok := FindTransition(from, to)
+	if !ok {
+		return &WorkflowError{
+			Code:    "transition_not_allowed",
+			Message: "Переход из статуса «" + NormalizeMarkerStatus(from) + "» в «" + NormalizeMarkerStatus(to) + "» не предусмотрен",
+		}
+	}
+	if !actor.allowed(t) {
+		return &WorkflowError{Code: "forbidden", Message: "Недостаточно прав для перехода «" + t.LabelRu + "»"}
+	}
+	if t.RequireNote && strings.TrimSpace(note) == "" {
+		return &WorkflowError{Code: "note_required", Message: "Для перехода «" + t.LabelRu + "» укажите причину (moderator_note)"}
+	}
+	if t.RequireImageAfter && !hasImageAfter {
+		return &WorkflowError{Code: "image_after_required", Message: "Для перехода «" + t.LabelRu + "» приложите фото после работ (image_after_url)"}
+	}
+	return nil
+}
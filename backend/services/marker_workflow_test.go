@@ -0,0 +1,82 @@
+package services
+
+import "testing"
+
+func TestCheckTransition(t *testing.T) {
+	mod := WorkflowActor{UserID: 1, IsModerator: true}
+	adm := WorkflowActor{UserID: 2, IsAdmin: true}
+	user := WorkflowActor{UserID: 3}
+	rep := WorkflowActor{UserID: 5, DepartmentRep: true}
+
+	cases := []struct {
+		name     string
+		from, to string
+		actor    WorkflowActor
+		note     string
+		image    bool
+		code     string
+	}{
+		{"approve", "pending", "approved", mod, "", false, ""},
+		{"empty status is pending", "", "approved", mod, "", false, ""},
+		{"reject needs note", "pending", "rejected", mod, "", false, "note_required"},
+		{"reject with note", "pending", "rejected", mod, "дубль", false, ""},
+		{"reject only from pending", "approved", "rejected", mod, "дубль", false, "transition_not_allowed"},
+		{"skip in_progress", "approved", "resolved", mod, "", true, "transition_not_allowed"},
+		{"resolve needs photo", "in_progress", "resolved", mod, "", false, "image_after_required"},
+		{"resolve with photo", "in_progress", "resolved", mod, "", true, ""},
+		{"reopen", "resolved", "in_progress", mod, "не сделано", false, ""},
+		{"restore rejected is admin only", "rejected", "pending", mod, "ошибка", false, "forbidden"},
+		{"admin restores rejected", "rejected", "pending", adm, "ошибка", false, ""},
+		{"plain user", "pending", "approved", user, "", false, "forbidden"},
+		{"same status", "approved", "approved", mod, "", false, "transition_not_allowed"},
+		{"moderator cannot contest", "resolved", "reopened", mod, "не сделано", false, "forbidden"},
+		{"verified contest", "resolved", "reopened", WorkflowActor{UserID: 4, ContestVerified: true}, "яма осталась", false, ""},
+		{"resolve reopened needs photo", "reopened", "resolved", mod, "", false, "image_after_required"},
+		{"department takes into work", "approved", "in_progress", rep, "", false, ""},
+		{"department resolves with photo", "in_progress", "resolved", rep, "", true, ""},
+		{"department cannot approve", "pending", "approved", rep, "", false, "forbidden"},
+		{"department cannot reject", "pending", "rejected", rep, "дубль", false, "forbidden"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckTransition(tc.from, tc.to, tc.actor, tc.note, tc.image)
+			if tc.code == "" {
+				if err != nil {
+					t.Fatalf("expected ok, got %v", err)
+				}
+				return
+			}
+			werr, ok := err.(*WorkflowError)
+			if !ok {
+				t.Fatalf("expected WorkflowError %q, got %v", tc.code, err)
+			}
+			if werr.Code != tc.code {
+				t.Errorf("expected code %q, got %q", tc.code, werr.Code)
+			}
+		})
+	}
+}
+
+func TestAvailableTransitions(t *testing.T) {
+	mod := WorkflowActor{UserID: 1, IsModerator: true}
+	list := AvailableTransitions("pending", mod)
+	if len(list) != 2 {
+		t.Fatalf("expected 2 transitions from pending, got %d", len(list))
+	}
+	if got := AvailableTransitions("rejected", mod); len(got) != 0 {
+		t.Errorf("moderator should not restore rejected markers, got %v", got)
+	}
+	if got := AvailableTransitions("pending", WorkflowActor{UserID: 5}); len(got) != 0 {
+		t.Errorf("plain user should have no transitions, got %v", got)
+	}
+}
+
+func TestMergedIsTerminal(t *testing.T) {
+	admin := WorkflowActor{UserID: 1, IsModerator: true, IsAdmin: true}
+	if got := AvailableTransitions(StatusMerged, admin); len(got) != 0 {
+		t.Errorf("merged markers must have no transitions, got %v", got)
+	}
+	if IsKnownMarkerStatus(StatusMerged) {
+		t.Error("merged must not be settable through the status endpoint")
+	}
+}
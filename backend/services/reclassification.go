@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"backend/repositories"
+)
+
+// Значения по умолчанию; переопределяются RECLASSIFY_POLL_SEC и RECLASSIFY_BATCH.
+const (
+	defaultReclassifyPoll  = 5 * time.Second
+	defaultReclassifyBatch = 20
+	reclassifyStuckAfter   = 10 * time.Minute
+)
+
+// StartReclassificationWorker запускает воркер очереди повторной классификации.
+// Несколько инстансов могут работать одновременно: задания разбираются через SKIP LOCKED.
+func StartReclassificationWorker() {
+	poll := envDuration("RECLASSIFY_POLL_SEC", time.Second, defaultReclassifyPoll)
+	batch := defaultReclassifyBatch
+	if n, err := strconv.Atoi(os.Getenv("RECLASSIFY_BATCH")); err == nil && n > 0 {
+		batch = n
+	}
+	go func() {
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		lastSweep := time.Time{}
+		for range ticker.C {
+			if time.Since(lastSweep) > reclassifyStuckAfter {
+				if n, err := repositories.RequeueStuckReclassificationJobs(reclassifyStuckAfter); err != nil {
+					log.Printf("reclassify requeue: %v", err)
+				} else if n > 0 {
+					log.Printf("reclassify: requeued %d stuck jobs", n)
+				}
+				lastSweep = time.Now()
+			}
+			// разбираем очередь, пока есть задания, затем ждём следующего тика
+			for RunReclassificationBatch(context.Background(), batch) == batch {
+			}
+		}
+	}()
+	log.Printf("Reclassification worker: poll %s, batch %d", poll, batch)
+}
+
+// RunReclassificationBatch обрабатывает до limit заданий; возвращает число взятых.
+func RunReclassificationBatch(ctx context.Context, limit int) int {
+	jobs, err := repositories.ClaimReclassificationJobs(limit)
+	if err != nil {
+		log.Printf("reclassify claim: %v", err)
+		return 0
+	}
+	for _, job := range jobs {
+		res, err := classifyMarker(ctx, job.Text, job.ImageURL)
+		if err == nil && (res == nil || res.Best == nil || res.Best.DomainKey == "") {
+			err = errors.New("no prediction")
+		}
+		if err != nil {
+			if ferr := repositories.FailReclassificationJob(job, err); ferr != nil {
+				log.Printf("reclassify fail job=%d: %v", job.ID, ferr)
+			}
+			continue
+		}
+		if err := repositories.CompleteReclassificationJob(job, res.Best.DomainKey, res.Best.GroupKey,
+			res.Best.IssueKey, res.Confidence()); err != nil {
+			log.Printf("reclassify complete job=%d: %v", job.ID, err)
+		}
+	}
+	return len(jobs)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"backend/repositories"
+)
+
+// Значения по умолчанию; переопределяются SLA_ESCALATION_INTERVAL_MIN и SLA_ADMIN_ESCALATION_HOURS.
+const (
+	defaultSLAEscalationInterval = 10 * time.Minute
+	defaultSLAAdminEscalation    = 72 * time.Hour
+)
+
+func envDuration(key string, unit, def time.Duration) time.Duration {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return time.Duration(n) * unit
+	}
+	return def
+}
+
+// StartSLAEscalationScheduler запускает фоновую проверку просроченных SLA.
+func StartSLAEscalationScheduler() {
+	interval := envDuration("SLA_ESCALATION_INTERVAL_MIN", time.Minute, defaultSLAEscalationInterval)
+	adminAfter := envDuration("SLA_ADMIN_ESCALATION_HOURS", time.Hour, defaultSLAAdminEscalation)
+	go func() {
+		RunSLAEscalation(time.Now(), adminAfter)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			RunSLAEscalation(now, adminAfter)
+		}
+	}()
+	log.Printf("SLA escalation scheduler: every %s, admins after %s", interval, adminAfter)
+}
+
+// slaEscalationBatch — сколько просроченных меток берётся за один запрос.
+const slaEscalationBatch = 500
+
+// Обращения к БД; в тестах подменяются.
+var (
+	listOverdueMarkers  = repositories.ListOverdueMarkers
+	claimSLAEscalation  = repositories.ClaimSLAEscalation
+	notifySLAEscalation = sendSLAEscalation
+)
+
+// RunSLAEscalation — один проход: уровень 1 (модераторы + ведомство) для всех просроченных,
+// уровень 2 (администраторы) для просроченных дольше adminAfter. Повторы отсекает ClaimSLAEscalation;
+// уже эскалированные метки выпадают из выборки, поэтому проход идёт порциями, пока они не кончатся.
+func RunSLAEscalation(now time.Time, adminAfter time.Duration) {
+	for {
+		overdue, err := listOverdueMarkers(now, now.Add(-adminAfter), slaEscalationBatch)
+		if err != nil {
+			log.Printf("sla escalation scan: %v", err)
+			return
+		}
+		claimed := 0
+		for _, o := range overdue {
+			if escalateOverdueMarker(o, repositories.SLALevelModerators) {
+				claimed++
+			}
+			if now.Sub(o.DueAt) >= adminAfter && escalateOverdueMarker(o, repositories.SLALevelAdmins) {
+				claimed++
+			}
+		}
+		// ни одной новой эскалации (ошибки БД) — следующая порция была бы той же самой
+		if len(overdue) < slaEscalationBatch || claimed == 0 {
+			return
+		}
+	}
+}
+
+// escalateOverdueMarker — true, если эскалация этого уровня зафиксирована сейчас.
+func escalateOverdueMarker(o repositories.OverdueMarker, level int) bool {
+	claimed, err := claimSLAEscalation(o.MarkerID, o.Kind, level, o.DueAt)
+	if err != nil {
+		log.Printf("sla escalation claim marker=%d: %v", o.MarkerID, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+	notifySLAEscalation(o, level)
+	return true
+}
+
+func sendSLAEscalation(o repositories.OverdueMarker, level int) {
+	audience := "moderators"
+	if level == repositories.SLALevelAdmins {
+		audience = "admins"
+	}
+	_ = repositories.InsertMarkerChange(o.MarkerID, "sla_escalation", "", o.Kind+":"+audience, nil)
+
+	snip := []rune(o.Text)
+	if len(snip) > 120 {
+		snip = append(snip[:120], '…')
+	}
+	what := "срок ответа"
+	if o.Kind == repositories.SLAKindResolution {
+		what = "срок решения"
+	}
+	title := "⏰ Просрочен " + what
+	body := "Обращение №" + strconv.Itoa(o.MarkerID) + ": " + what + " истёк " + o.DueAt.Format("02.01.2006 15:04") + ".\n\n«" + string(snip) + "»"
+	notifType := "sla_overdue"
+	if level == repositories.SLALevelAdmins {
+		title = "🚨 Эскалация: " + what + " давно истёк"
+		notifType = "sla_escalation_admin"
+	}
+
+	staff, err := repositories.StaffUserIDs(level == repositories.SLALevelAdmins)
+	if err != nil {
+		log.Printf("sla escalation staff: %v", err)
+	}
+	notifyUsers(staff, o.MarkerID, notifType, title, body)
+	if level == repositories.SLALevelModerators && o.Kind == repositories.SLAKindResolution {
+		NotifyDepartmentReps(o.MarkerID, notifType, title, body)
+	}
+}
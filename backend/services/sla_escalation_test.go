@@ -0,0 +1,117 @@
+package services
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"backend/repositories"
+)
+
+type escalationKey struct {
+	markerID int
+	kind     string
+	level    int
+	dueAt    time.Time
+}
+
+// fakeEscalationStore повторяет ListOverdueMarkers/ClaimSLAEscalation поверх памяти:
+// метка попадает в выборку, пока по ней не хватает эскалации нужного уровня.
+type fakeEscalationStore struct {
+	overdue []repositories.OverdueMarker
+	claimed map[escalationKey]bool
+	batches []int
+}
+
+func (s *fakeEscalationStore) list(before, adminBefore time.Time, limit int) ([]repositories.OverdueMarker, error) {
+	var out []repositories.OverdueMarker
+	for _, o := range s.overdue {
+		if !o.DueAt.Before(before) {
+			continue
+		}
+		needL1 := !s.claimed[escalationKey{o.MarkerID, o.Kind, repositories.SLALevelModerators, o.DueAt}]
+		needL2 := o.DueAt.Before(adminBefore) &&
+			!s.claimed[escalationKey{o.MarkerID, o.Kind, repositories.SLALevelAdmins, o.DueAt}]
+		if needL1 || needL2 {
+			out = append(out, o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DueAt.Before(out[j].DueAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	s.batches = append(s.batches, len(out))
+	return out, nil
+}
+
+func (s *fakeEscalationStore) claim(markerID int, kind string, level int, dueAt time.Time) (bool, error) {
+	k := escalationKey{markerID, kind, level, dueAt}
+	if s.claimed[k] {
+		return false, nil
+	}
+	s.claimed[k] = true
+	return true, nil
+}
+
+func withFakeEscalationStore(t *testing.T, s *fakeEscalationStore) map[int]int {
+	t.Helper()
+	notified := map[int]int{}
+	prevList, prevClaim, prevNotify := listOverdueMarkers, claimSLAEscalation, notifySLAEscalation
+	listOverdueMarkers = s.list
+	claimSLAEscalation = s.claim
+	notifySLAEscalation = func(o repositories.OverdueMarker, level int) { notified[level]++ }
+	t.Cleanup(func() {
+		listOverdueMarkers, claimSLAEscalation, notifySLAEscalation = prevList, prevClaim, prevNotify
+	})
+	return notified
+}
+
+func TestRunSLAEscalationSkipsEscalatedMarkers(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := &fakeEscalationStore{claimed: map[escalationKey]bool{}}
+	// старых просрочек больше, чем влезает в одну порцию, и все они уже эскалированы на уровень 1
+	for i := 1; i <= slaEscalationBatch+20; i++ {
+		o := repositories.OverdueMarker{MarkerID: i, Kind: repositories.SLAKindResponse, DueAt: now.Add(-time.Duration(i) * time.Minute)}
+		store.overdue = append(store.overdue, o)
+		store.claimed[escalationKey{o.MarkerID, o.Kind, repositories.SLALevelModerators, o.DueAt}] = true
+	}
+	fresh := repositories.OverdueMarker{MarkerID: 10000, Kind: repositories.SLAKindResolution, DueAt: now.Add(-time.Second)}
+	store.overdue = append(store.overdue, fresh)
+
+	notified := withFakeEscalationStore(t, store)
+	RunSLAEscalation(now, 72*time.Hour)
+
+	if !store.claimed[escalationKey{fresh.MarkerID, fresh.Kind, repositories.SLALevelModerators, fresh.DueAt}] {
+		t.Fatalf("fresh breach was not escalated (batches %v)", store.batches)
+	}
+	if notified[repositories.SLALevelModerators] != 1 {
+		t.Fatalf("level 1 notifications = %d, want 1", notified[repositories.SLALevelModerators])
+	}
+
+	store.batches = nil
+	RunSLAEscalation(now, 72*time.Hour)
+	if len(store.batches) != 1 || store.batches[0] != 0 {
+		t.Fatalf("second run batches = %v, want one empty batch", store.batches)
+	}
+}
+
+func TestRunSLAEscalationDrainsSeveralBatches(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := &fakeEscalationStore{claimed: map[escalationKey]bool{}}
+	total := 2*slaEscalationBatch + 7
+	for i := 1; i <= total; i++ {
+		store.overdue = append(store.overdue, repositories.OverdueMarker{
+			MarkerID: i, Kind: repositories.SLAKindResponse, DueAt: now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+
+	notified := withFakeEscalationStore(t, store)
+	RunSLAEscalation(now, 72*time.Hour)
+
+	if notified[repositories.SLALevelModerators] != total {
+		t.Fatalf("level 1 notifications = %d, want %d (batches %v)", notified[repositories.SLALevelModerators], total, store.batches)
+	}
+	if len(store.batches) != 3 {
+		t.Fatalf("batches = %v, want 3", store.batches)
+	}
+}
@@ -41,6 +41,9 @@ func HandleMarkerStatusChange(markerID, ownerID int, oldStatus, newStatus string
 		body = "Модератор отметил обращение как решённое.\n\n«" + snip + "»"
 		markerIDPtr := markerID
 		AwardPoints(ownerID, "marker_resolved", PointsMarkerResolved, "Обращение решено", &markerIDPtr)
+	case newStatus == "reopened":
+		title = "🔁 Решение по обращению оспорено"
+		body = "Обращение снова открыто и передано ведомству на повторную проверку.\n\n«" + snip + "»"
 	case newStatus == "rejected":
 		title = "❌ Ваше обращение отклонено"
 		body = "Модератор отклонил заявку.\n\n«" + snip + "»"
@@ -73,3 +76,42 @@ func HandleMarkerStatusChange(markerID, ownerID int, oldStatus, newStatus string
 		}
 	}
 }
+
+// NotifyDepartmentReps — уведомления представителям ведомства, отвечающего за метку.
+func NotifyDepartmentReps(markerID int, notifType, title, body string) {
+	ids, err := repositories.DepartmentRepIDsForMarker(markerID)
+	if err != nil {
+		log.Printf("department reps marker=%d: %v", markerID, err)
+		return
+	}
+	notifyUsers(ids, markerID, notifType, title, body)
+}
+
+// notifyUsers — уведомление в БД + push через WebSocket каждому из списка.
+func notifyUsers(userIDs []int, markerID int, notifType, title, body string) {
+	nrepo := repositories.NewNotificationRepository()
+	mid := markerID
+	for _, uid := range userIDs {
+		nid, err := nrepo.Create(uid, notifType, &mid, title, body)
+		if err != nil {
+			log.Printf("notify user=%d: %v", uid, err)
+			continue
+		}
+		realtime.BroadcastToUser(uid, realtime.Event{
+			Type: realtime.EventNotification,
+			Payload: map[string]interface{}{
+				"id": nid, "title": title, "body": body, "marker_id": markerID, "notif_type": notifType,
+			},
+		})
+	}
+}
+
+// NotifyDepartment — уведомления представителям конкретного ведомства по метке.
+func NotifyDepartment(departmentID, markerID int, notifType, title, body string) {
+	ids, err := repositories.DepartmentRepIDs(departmentID)
+	if err != nil {
+		log.Printf("department reps dept=%d: %v", departmentID, err)
+		return
+	}
+	notifyUsers(ids, markerID, notifType, title, body)
+}
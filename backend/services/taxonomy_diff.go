@@ -0,0 +1,125 @@
+package services
+
+import (
+	"sort"
+
+	"backend/models"
+)
+
+// TaxonomyFieldChange — поле узла таксономии до и после.
+type TaxonomyFieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// TaxonomyNodeDiff — изменение направления, группы или проблемы. Path: domain, domain/group, domain/group/issue.
+type TaxonomyNodeDiff struct {
+	Path           string                `json:"path"`
+	Level          string                `json:"level"`
+	LabelRu        string                `json:"label_ru,omitempty"`
+	Fields         []TaxonomyFieldChange `json:"fields,omitempty"`
+	PhrasesAdded   []string              `json:"phrases_added,omitempty"`
+	PhrasesRemoved []string              `json:"phrases_removed,omitempty"`
+}
+
+type TaxonomyDiff struct {
+	Added   []TaxonomyNodeDiff `json:"added"`
+	Removed []TaxonomyNodeDiff `json:"removed"`
+	Changed []TaxonomyNodeDiff `json:"changed"`
+}
+
+type taxonomyNode struct {
+	level          string
+	label          string
+	icon           string
+	resolutionDays int
+	position       int
+	phrases        []string
+}
+
+func flattenTaxonomy(tax *models.Taxonomy) (map[string]taxonomyNode, []string) {
+	nodes := map[string]taxonomyNode{}
+	var order []string
+	if tax == nil {
+		return nodes, order
+	}
+	add := func(path string, n taxonomyNode) {
+		nodes[path] = n
+		order = append(order, path)
+	}
+	for di, d := range tax.Domains {
+		add(d.Key, taxonomyNode{level: "domain", label: d.LabelRu, icon: d.MarkerIcon,
+			resolutionDays: d.ResolutionDays, position: di, phrases: d.TrainingPhrasesRu})
+		for gi, g := range d.Groups {
+			gp := d.Key + "/" + g.Key
+			add(gp, taxonomyNode{level: "group", label: g.LabelRu, position: gi, phrases: g.TrainingPhrasesRu})
+			for ii, is := range g.Issues {
+				add(gp+"/"+is.Key, taxonomyNode{level: "issue", label: is.LabelRu, position: ii, phrases: is.TrainingPhrasesRu})
+			}
+		}
+	}
+	return nodes, order
+}
+
+// DiffTaxonomies сравнивает две версии таксономии поузлово.
+func DiffTaxonomies(from, to *models.Taxonomy) TaxonomyDiff {
+	a, aOrder := flattenTaxonomy(from)
+	b, bOrder := flattenTaxonomy(to)
+	diff := TaxonomyDiff{Added: []TaxonomyNodeDiff{}, Removed: []TaxonomyNodeDiff{}, Changed: []TaxonomyNodeDiff{}}
+	for _, path := range aOrder {
+		if _, ok := b[path]; !ok {
+			n := a[path]
+			diff.Removed = append(diff.Removed, TaxonomyNodeDiff{Path: path, Level: n.level, LabelRu: n.label})
+		}
+	}
+	for _, path := range bOrder {
+		nb := b[path]
+		na, ok := a[path]
+		if !ok {
+			diff.Added = append(diff.Added, TaxonomyNodeDiff{Path: path, Level: nb.level, LabelRu: nb.label,
+				PhrasesAdded: nb.phrases})
+			continue
+		}
+		d := TaxonomyNodeDiff{Path: path, Level: nb.level, LabelRu: nb.label}
+		if na.label != nb.label {
+			d.Fields = append(d.Fields, TaxonomyFieldChange{"label_ru", na.label, nb.label})
+		}
+		if na.icon != nb.icon {
+			d.Fields = append(d.Fields, TaxonomyFieldChange{"marker_icon", na.icon, nb.icon})
+		}
+		if na.resolutionDays != nb.resolutionDays {
+			d.Fields = append(d.Fields, TaxonomyFieldChange{"resolution_days", na.resolutionDays, nb.resolutionDays})
+		}
+		if na.position != nb.position {
+			d.Fields = append(d.Fields, TaxonomyFieldChange{"sort_order", na.position, nb.position})
+		}
+		d.PhrasesAdded, d.PhrasesRemoved = diffPhrases(na.phrases, nb.phrases)
+		if len(d.Fields) > 0 || len(d.PhrasesAdded) > 0 || len(d.PhrasesRemoved) > 0 {
+			diff.Changed = append(diff.Changed, d)
+		}
+	}
+	return diff
+}
+
+func diffPhrases(old, new []string) (added, removed []string) {
+	inOld := map[string]bool{}
+	for _, p := range old {
+		inOld[p] = true
+	}
+	inNew := map[string]bool{}
+	for _, p := range new {
+		inNew[p] = true
+		if !inOld[p] {
+			added = append(added, p)
+		}
+	}
+	for _, p := range old {
+		if !inNew[p] {
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+
+	"backend/models"
+)
+
+func TestDiffTaxonomies(t *testing.T) {
+	from := &models.Taxonomy{Domains: []models.TaxonomyDomain{
+		{Key: "roads", LabelRu: "Дороги", MarkerIcon: "islands#redIcon", ResolutionDays: 15,
+			TrainingPhrasesRu: []string{"яма", "трещина"},
+			Groups:            []models.TaxonomyGroup{{Key: "surface", LabelRu: "Покрытие"}}},
+		{Key: "light", LabelRu: "Освещение", ResolutionDays: 5},
+	}}
+	to := &models.Taxonomy{Domains: []models.TaxonomyDomain{
+		{Key: "roads", LabelRu: "Дорожная сеть", MarkerIcon: "islands#redIcon", ResolutionDays: 10,
+			TrainingPhrasesRu: []string{"яма", "колея"},
+			Groups: []models.TaxonomyGroup{{Key: "surface", LabelRu: "Покрытие",
+				Issues: []models.TaxonomyIssue{{Key: "pothole", LabelRu: "Яма"}}}}},
+		{Key: "parks", LabelRu: "Парки", ResolutionDays: 20},
+	}}
+
+	d := DiffTaxonomies(from, to)
+	if len(d.Removed) != 1 || d.Removed[0].Path != "light" {
+		t.Fatalf("removed = %+v", d.Removed)
+	}
+	if len(d.Added) != 2 || d.Added[0].Path != "roads/surface/pothole" || d.Added[1].Path != "parks" {
+		t.Fatalf("added = %+v", d.Added)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].Path != "roads" {
+		t.Fatalf("changed = %+v", d.Changed)
+	}
+	c := d.Changed[0]
+	if len(c.Fields) != 2 || c.Fields[0].Field != "label_ru" || c.Fields[1].Field != "resolution_days" {
+		t.Errorf("fields = %+v", c.Fields)
+	}
+	if len(c.PhrasesAdded) != 1 || c.PhrasesAdded[0] != "колея" || len(c.PhrasesRemoved) != 1 || c.PhrasesRemoved[0] != "трещина" {
+		t.Errorf("phrases +%v -%v", c.PhrasesAdded, c.PhrasesRemoved)
+	}
+
+	if same := DiffTaxonomies(to, to); len(same.Added)+len(same.Removed)+len(same.Changed) != 0 {
+		t.Errorf("identical taxonomies differ: %+v", same)
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"backend/models"
+	"backend/repositories"
+)
+
+// TaxonomyImportPlan — что изменит импорт: узлы таксономии, обращения на удаляемых категориях, ошибки проверки.
+type TaxonomyImportPlan struct {
+	TaxonomyDiff
+	MarkersAffected map[string]int `json:"markers_affected"`
+	Errors          []string       `json:"errors"`
+	Warnings        []string       `json:"warnings"`
+	// Blocked — удаление категорий с обращениями: импорт не применится, пока их не перенесут
+	Blocked bool `json:"blocked"`
+}
+
+// PlanTaxonomyImport нормализует tax и сравнивает его с текущей таксономией.
+func PlanTaxonomyImport(tax *models.Taxonomy) (*TaxonomyImportPlan, error) {
+	plan := &TaxonomyImportPlan{MarkersAffected: map[string]int{}, Errors: []string{}, Warnings: []string{}}
+	errs, warnings := repositories.ValidateTaxonomyImport(tax)
+	plan.Errors = append(plan.Errors, errs...)
+	plan.Warnings = append(plan.Warnings, warnings...)
+	current, err := repositories.ListTaxonomy()
+	if err != nil {
+		return nil, err
+	}
+	plan.TaxonomyDiff = DiffTaxonomies(current, tax)
+	if len(plan.Errors) > 0 {
+		return plan, nil
+	}
+	affected, err := repositories.MarkersOrphanedByTaxonomy(tax)
+	if err != nil {
+		return nil, err
+	}
+	plan.MarkersAffected = affected
+	plan.Blocked = len(affected) > 0
+	return plan, nil
+}
+
+// NeedsReclassification — новые категории или другие обучающие фразы могут поменять ответ классификатора.
+func (p *TaxonomyImportPlan) NeedsReclassification() bool {
+	if len(p.Added) > 0 {
+		return true
+	}
+	for _, c := range p.Changed {
+		if len(c.PhrasesAdded) > 0 || len(c.PhrasesRemoved) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Веса оценки дубля: близость, совпадение направления, похожесть текста.
+const (
+	DuplicateWeightDistance = 0.35
+	DuplicateWeightDomain   = 0.25
+	DuplicateWeightText     = 0.40
+)
+
+// Trigrams — множество триграмм как в pg_trgm: слова в нижнем регистре, дополненные "  " слева и " " справа.
+func Trigrams(s string) map[string]struct{} {
+	out := map[string]struct{}{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		rs := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(rs); i++ {
+			out[string(rs[i:i+3])] = struct{}{}
+		}
+	}
+	return out
+}
+
+// TrigramSimilarity — доля общих триграмм (0..1), аналог similarity() из pg_trgm.
+func TrigramSimilarity(a, b string) float64 {
+	ta, tb := Trigrams(a), Trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// DomainMatch — 1 при совпадении направлений, 0 при разных, 0.5 если одно из них неизвестно.
+func DomainMatch(a, b string) float64 {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	switch {
+	case a == "" || b == "":
+		return 0.5
+	case a == b:
+		return 1
+	}
+	return 0
+}
+
+// DuplicateScore сводит расстояние (в пределах radiusM), совпадение направления и похожесть текста в оценку 0..1.
+func DuplicateScore(distanceM, radiusM, domainMatch, textSimilarity float64) float64 {
+	if radiusM <= 0 || distanceM > radiusM {
+		return 0
+	}
+	closeness := 1 - distanceM/radiusM
+	return DuplicateWeightDistance*closeness + DuplicateWeightDomain*domainMatch + DuplicateWeightText*textSimilarity
+}
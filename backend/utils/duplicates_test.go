@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestTrigramSimilarity(t *testing.T) {
+	if got := TrigramSimilarity("Большая яма на дороге", "большая яма на дороге!"); got != 1 {
+		t.Errorf("identical text: got %v", got)
+	}
+	if got := TrigramSimilarity("яма", ""); got != 0 {
+		t.Errorf("empty text: got %v", got)
+	}
+	close := TrigramSimilarity("глубокая яма у остановки", "яма у остановки глубокая очень")
+	far := TrigramSimilarity("глубокая яма у остановки", "сломана скамейка в парке")
+	if close <= far || close < 0.5 {
+		t.Errorf("close=%v far=%v", close, far)
+	}
+}
+
+func TestDuplicateScore(t *testing.T) {
+	const threshold = 0.6
+	// яма и сломанная скамейка в 10 м друг от друга — не дубль
+	pothole := "глубокая яма на проезжей части"
+	bench := "сломана скамейка во дворе"
+	if s := DuplicateScore(10, 300, DomainMatch("roads", "yards"), TrigramSimilarity(pothole, bench)); s >= threshold {
+		t.Errorf("different issues nearby scored %v", s)
+	}
+	// одинаковые обращения в 150 м — дубль
+	if s := DuplicateScore(150, 300, DomainMatch("roads", "roads"), TrigramSimilarity(pothole, "глубокая яма на проезжей части!")); s < threshold {
+		t.Errorf("same issue 150 m apart scored %v", s)
+	}
+	if s := DuplicateScore(400, 300, 1, 1); s != 0 {
+		t.Errorf("outside radius scored %v", s)
+	}
+}
@@ -0,0 +1,74 @@
+package utils
+
+import "time"
+
+const workCalendarDayLayout = "2006-01-02"
+
+// WorkCalendar — рабочие дни для отсчёта SLA: выходные дни недели, праздники и перенесённые рабочие дни.
+type WorkCalendar struct {
+	Weekend  map[time.Weekday]bool
+	Holidays map[string]bool // "2006-01-02" — нерабочие дни вне выходных
+	Workdays map[string]bool // "2006-01-02" — рабочие дни, выпавшие на выходной (переносы)
+}
+
+func NewWorkCalendar(weekend []time.Weekday) *WorkCalendar {
+	c := &WorkCalendar{
+		Weekend:  map[time.Weekday]bool{},
+		Holidays: map[string]bool{},
+		Workdays: map[string]bool{},
+	}
+	if len(weekend) == 0 {
+		weekend = []time.Weekday{time.Saturday, time.Sunday}
+	}
+	for _, d := range weekend {
+		c.Weekend[d] = true
+	}
+	return c
+}
+
+// ISOWeekday — 1 = пн … 7 = вс (как EXTRACT(ISODOW) в PostgreSQL).
+func ISOWeekday(n int) (time.Weekday, bool) {
+	if n < 1 || n > 7 {
+		return 0, false
+	}
+	return time.Weekday(n % 7), true
+}
+
+func (c *WorkCalendar) SetDay(day time.Time, isWorkday bool) {
+	key := day.Format(workCalendarDayLayout)
+	if isWorkday {
+		c.Workdays[key] = true
+		delete(c.Holidays, key)
+		return
+	}
+	c.Holidays[key] = true
+	delete(c.Workdays, key)
+}
+
+func (c *WorkCalendar) IsWorkday(t time.Time) bool {
+	key := t.Format(workCalendarDayLayout)
+	if c.Workdays[key] {
+		return true
+	}
+	if c.Holidays[key] {
+		return false
+	}
+	return !c.Weekend[t.Weekday()]
+}
+
+// AddWorkdays сдвигает момент на days рабочих дней, сохраняя время суток.
+// Обращение, поданное в выходной, начинает отсчёт с ближайшего рабочего дня.
+func (c *WorkCalendar) AddWorkdays(from time.Time, days int) time.Time {
+	if c == nil {
+		c = NewWorkCalendar(nil)
+	}
+	t := from
+	// защита от календаря без рабочих дней
+	for i := 0; days > 0 && i < 3660; i++ {
+		t = t.AddDate(0, 0, 1)
+		if c.IsWorkday(t) {
+			days--
+		}
+	}
+	return t
+}
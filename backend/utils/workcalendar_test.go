@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddWorkdaysSkipsWeekendAndHolidays(t *testing.T) {
+	c := NewWorkCalendar(nil)
+	c.SetDay(time.Date(2026, 6, 12, 0, 0, 0, 0, time.UTC), false)
+
+	// чт 11.06 10:00 + 3 рабочих дня: пт 12.06 праздник, сб/вс выходные → пн 15, вт 16, ср 17
+	from := time.Date(2026, 6, 11, 10, 0, 0, 0, time.UTC)
+	got := c.AddWorkdays(from, 3)
+	want := time.Date(2026, 6, 17, 10, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAddWorkdaysTransferredWorkday(t *testing.T) {
+	c := NewWorkCalendar(nil)
+	c.SetDay(time.Date(2026, 6, 13, 0, 0, 0, 0, time.UTC), true)
+
+	from := time.Date(2026, 6, 12, 9, 0, 0, 0, time.UTC)
+	got := c.AddWorkdays(from, 1)
+	want := time.Date(2026, 6, 13, 9, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAddWorkdaysDepartmentWeekend(t *testing.T) {
+	// ведомство работает без выходных, кроме воскресенья
+	c := NewWorkCalendar([]time.Weekday{time.Sunday})
+	from := time.Date(2026, 6, 12, 9, 0, 0, 0, time.UTC) // пт
+	got := c.AddWorkdays(from, 2)
+	want := time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC) // сб, (вс), пн
+	if !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestISOWeekday(t *testing.T) {
+	if d, ok := ISOWeekday(7); !ok || d != time.Sunday {
+		t.Fatalf("7 → %v %v", d, ok)
+	}
+	if d, ok := ISOWeekday(1); !ok || d != time.Monday {
+		t.Fatalf("1 → %v %v", d, ok)
+	}
+	if _, ok := ISOWeekday(0); ok {
+		t.Fatal("0 must be rejected")
+	}
+}
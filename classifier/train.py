@@ -4,6 +4,7 @@
 Базовые примеры — из ../issue-taxonomy.json (поле training_phrases_ru).
 Готовый расширенный набор — data/ready_train.csv (уже в репозитории).
 Свои примеры — data/user_labeled.csv (колонки text,label; label = ключ направления: roads, transit, …).
+Разметку модераторов можно выгрузить из бэкенда: GET /api/admin/classification-labels/export.
 
 Готовые крупные датасеты (в основном EN, нужна своя маппинг-таблица на ваши классы):
 - NYC 311 Service Requests — https://data.cityofnewyork.us/ (Complaint Type / Descriptor)
@@ -60,6 +61,12 @@ def load_rows_from_taxonomy() -> list[dict]:
         leaf = d["key"]
         for phrase in d.get("training_phrases_ru", []):
             rows.append({"text": phrase.strip(), "label": leaf})
+        # группы и проблемы уточняют направление, метка класса остаётся ключом направления
+        for g in d.get("groups", []):
+            nodes = [g, *g.get("issues", [])]
+            for node in nodes:
+                for phrase in node.get("training_phrases_ru", []):
+                    rows.append({"text": phrase.strip(), "label": leaf})
     return rows
 
 
@@ -3,12 +3,16 @@ import { AuthContext } from "../Auth/AuthContext.jsx";
 import { useRealtime } from "../../hooks/useRealtime.js";
 import { showToast } from "../ToastHost.jsx";
 
+const ACHIEVEMENTS_ONLY = [{ topic: "markers", ids: [] }];
+
 /** Toast при новом достижении через WebSocket. */
 export default function AchievementListener() {
   const { user } = useContext(AuthContext);
 
   useRealtime({
     enabled: !!user,
+    // личные события приходят без подписки; пустая тема отключает поток меток
+    subscriptions: ACHIEVEMENTS_ONLY,
     onAchievement: (payload) => {
       const title = payload?.title || "Новое достижение!";
       showToast(title, "success");
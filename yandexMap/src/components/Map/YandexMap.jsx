@@ -291,6 +291,7 @@ const YandexMap = () => {
   });
   const [showHeatmap, setShowHeatmap] = useState(false);
   const [mapReady, setMapReady] = useState(false);
+  const [realtimeBBox, setRealtimeBBox] = useState(null);
   const [heatmapLoading, setHeatmapLoading] = useState(false);
   /** Тот же объект ymaps, что использует карта (не обязательно равен window.ymaps). */
   const [ymapsApi, setYmapsApi] = useState(null);
@@ -924,10 +925,13 @@ const YandexMap = () => {
       try {
         const c = map.getCenter();
         saveMapView([c[0], c[1]], map.getZoom());
+        const [[swLat, swLng], [neLat, neLng]] = map.getBounds();
+        setRealtimeBBox({ sw_lat: swLat, sw_lng: swLng, ne_lat: neLat, ne_lng: neLng });
       } catch {
         /* */
       }
     };
+    onBounds();
     map.events.add("boundschange", onBounds);
     window.addEventListener("resize", fitMap);
 
@@ -988,6 +992,7 @@ const YandexMap = () => {
 
   useRealtime({
     enabled: !!user,
+    subscriptions: realtimeBBox ? [{ topic: "viewport", bbox: realtimeBBox }] : undefined,
     onMarkerCreated: () => loadMarkersRef.current?.(),
     onMarkerUpdated: (p) => {
       loadMarkersRef.current?.();
@@ -996,6 +1001,10 @@ const YandexMap = () => {
     onNotification: () => {
       window.dispatchEvent(new CustomEvent("yandexmap:notifications"));
     },
+    onResync: () => {
+      loadMarkersRef.current?.();
+      window.dispatchEvent(new CustomEvent("yandexmap:notifications"));
+    },
   });
 
   const handleMapClick = (event) => {
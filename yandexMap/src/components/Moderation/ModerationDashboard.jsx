@@ -19,6 +19,7 @@ import {
   uploadImage,
 } from "../../services/api.js";
 import { useTaxonomy } from "../../hooks/useTaxonomy.js";
+import { useRealtime } from "../../hooks/useRealtime.js";
 import "../Profile/Profile.css";
 import "./Moderation.css";
 import "./Moderation.v2.css";
@@ -36,6 +37,10 @@ import ModerationSpamList from "./ModerationSpamList.jsx";
 import ModerationSpamPreview from "./ModerationSpamPreview.jsx";
 import { normStatus } from "./moderationUtils.js";
 
+const MODERATION_TOPICS = [{ topic: "moderation" }];
+// отметка «проверяет» живёт на сервере 90 с — продлеваем заметно чаще
+const REVIEW_RENEW_MS = 30000;
+
 function RejectDialog({ title, note, setNote, onCancel, onConfirm, busy }) {
   return (
     <div
@@ -157,6 +162,7 @@ export default function ModerationDashboard() {
   const [selectedIds, setSelectedIds] = useState([]);
   const [busyId, setBusyId] = useState(null);
   const [bulkBusy, setBulkBusy] = useState(false);
+  const [markerReviews, setMarkerReviews] = useState({});
   const [rejectTarget, setRejectTarget] = useState(null);
   const [rejectNote, setRejectNote] = useState("");
   const [bulkRejectOpen, setBulkRejectOpen] = useState(false);
@@ -273,6 +279,47 @@ export default function ModerationDashboard() {
 
   const focusedMarker = markers[focusIndex] || null;
   const focusedReport = reports[focusIndex] || null;
+  const reviewMarkerId = !isSpamTab && focusedMarker ? focusedMarker.id : null;
+  const reviewMarkerRef = useRef(null);
+  reviewMarkerRef.current = reviewMarkerId;
+
+  const { send: sendRealtime } = useRealtime({
+    enabled: !!user,
+    subscriptions: MODERATION_TOPICS,
+    onMarkerReview: (p) => {
+      if (!p?.marker_id) return;
+      setMarkerReviews((prev) => ({ ...prev, [p.marker_id]: p.reviewers || [] }));
+    },
+    // после (пере)подключения сервер не помнит открытую метку
+    onOpen: () => {
+      if (reviewMarkerRef.current) {
+        sendRealtime({ action: "review", marker_id: reviewMarkerRef.current });
+      }
+    },
+  });
+
+  useEffect(() => {
+    if (!reviewMarkerId) return undefined;
+    sendRealtime({ action: "review", marker_id: reviewMarkerId });
+    const timer = setInterval(
+      () => sendRealtime({ action: "review", marker_id: reviewMarkerId }),
+      REVIEW_RENEW_MS
+    );
+    return () => {
+      clearInterval(timer);
+      sendRealtime({ action: "release", marker_id: reviewMarkerId });
+    };
+  }, [reviewMarkerId, sendRealtime]);
+
+  const focusedReviewers = useMemo(
+    () =>
+      reviewMarkerId
+        ? (markerReviews[reviewMarkerId] || []).filter(
+            (r) => r.user_id !== user?.id
+          )
+        : [],
+    [markerReviews, reviewMarkerId, user]
+  );
 
   const resolveAbuse = useCallback(
     async (reportId, abuseStatus) => {
@@ -295,7 +342,15 @@ export default function ModerationDashboard() {
       setBusyId(id);
       setError("");
       try {
-        await patchMarkerStatus(id, status, note, imageAfterUrl);
+        try {
+          await patchMarkerStatus(id, status, note, imageAfterUrl);
+        } catch (e) {
+          // мягкая блокировка: другой модератор открыл метку раньше
+          if (e.code !== "marker_locked" || !window.confirm(`${e.message}\n\nИзменить статус всё равно?`)) {
+            throw e;
+          }
+          await patchMarkerStatus(id, status, note, imageAfterUrl, { force: true });
+        }
         await loadQueue();
         window.dispatchEvent(new Event("yandexmap:notifications"));
       } catch (e) {
@@ -513,6 +568,7 @@ export default function ModerationDashboard() {
             ) : (
               <ModerationPreview
                 marker={focusedMarker}
+                reviewers={focusedReviewers}
                 taxonomy={taxonomy}
                 busy={busyId != null}
                 onApprove={(m) => patchStatus(m.id, "approved")}
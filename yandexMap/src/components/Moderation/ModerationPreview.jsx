@@ -21,6 +21,7 @@ import {
 
 function ModerationPreview({
   marker,
+  reviewers = [],
   taxonomy,
   busy,
   onApprove,
@@ -151,6 +152,11 @@ function ModerationPreview({
       </header>
 
       <div className="mod-v2-preview__scroll">
+        {reviewers.length > 0 ? (
+          <p className="mod-v2-preview__reviewers" role="status">
+            Проверяет: {reviewers.map((r) => r.name || `модератор #${r.user_id}`).join(", ")}
+          </p>
+        ) : null}
         {catLine ? (
           <p className="mod-v2-preview__chip">{catLine}</p>
         ) : null}
@@ -1,9 +1,19 @@
-import { useEffect, useRef } from "react";
+import { useCallback, useEffect, useRef } from "react";
 import { WS_URL } from "../config.js";
 import { getToken } from "../services/api.js";
 
+const SEEN_SEQ_LIMIT = 500;
+
 /**
  * WebSocket: marker_created, marker_updated, notification, moderation_presence
+ *
+ * subscriptions — темы, например [{ topic: "viewport", bbox: { sw_lat, sw_lng, ne_lat, ne_lng } }].
+ * Без подписок сервер присылает все события города.
+ *
+ * После переподключения хук просит сервер догрузить пропущенное (resume с последним seq);
+ * если разрыв слишком большой, вызывается onResync — данные нужно перезагрузить целиком.
+ *
+ * Возвращает { send } — отправка сообщения серверу (например, { action: "review", marker_id }).
  */
 export function useRealtime({
   onMarkerCreated,
@@ -11,14 +21,26 @@ export function useRealtime({
   onNotification,
   onModerationPresence,
   onAchievement,
+  onResync,
+  onMarkerReview,
+  onOpen,
+  subscriptions,
   enabled = true,
 }) {
+  const wsRef = useRef(null);
+  const subsRef = useRef(subscriptions);
+  subsRef.current = subscriptions;
+  const subsKey = JSON.stringify(subscriptions || []);
+
   const handlers = useRef({
     onMarkerCreated,
     onMarkerUpdated,
     onNotification,
     onModerationPresence,
     onAchievement,
+    onResync,
+    onMarkerReview,
+    onOpen,
   });
   handlers.current = {
     onMarkerCreated,
@@ -26,6 +48,9 @@ export function useRealtime({
     onNotification,
     onModerationPresence,
     onAchievement,
+    onResync,
+    onMarkerReview,
+    onOpen,
   };
 
   useEffect(() => {
@@ -36,15 +61,37 @@ export function useRealtime({
     let ws;
     let closed = false;
     let retryMs = 2000;
+    let lastSeq = 0;
+    // сервер отдаёт события по возрастанию seq (меньший номер, опубликованный позже, ждёт своей
+    // очереди), поэтому наибольший полученный seq — безопасная точка для resume; повтор может
+    // продублировать живые события
+    const seen = new Set();
 
     const connect = () => {
       if (closed) return;
       ws = new WebSocket(`${WS_URL}?token=${encodeURIComponent(token)}`);
+      wsRef.current = ws;
+      ws.onopen = () => {
+        retryMs = 2000;
+        sendSubscriptions(ws, subsRef.current);
+        if (lastSeq > 0) ws.send(JSON.stringify({ action: "resume", since: lastSeq }));
+        handlers.current.onOpen?.();
+      };
       ws.onmessage = (ev) => {
         try {
           const msg = JSON.parse(ev.data);
           const h = handlers.current;
+          if (msg.seq) {
+            if (seen.has(msg.seq)) return;
+            seen.add(msg.seq);
+            if (seen.size > SEEN_SEQ_LIMIT) seen.delete(seen.values().next().value);
+            lastSeq = Math.max(lastSeq, msg.seq);
+          }
           switch (msg.type) {
+            case "resync_required":
+              if (msg.payload?.last_seq) lastSeq = msg.payload.last_seq;
+              h.onResync?.();
+              break;
             case "marker_created":
               h.onMarkerCreated?.(msg.payload);
               break;
@@ -57,6 +104,9 @@ export function useRealtime({
             case "moderation_presence":
               h.onModerationPresence?.(msg.payload);
               break;
+            case "marker_review":
+              h.onMarkerReview?.(msg.payload);
+              break;
             case "achievement_earned":
               h.onAchievement?.(msg.payload);
               break;
@@ -83,6 +133,7 @@ export function useRealtime({
     connect();
     return () => {
       closed = true;
+      wsRef.current = null;
       try {
         ws?.close();
       } catch {
@@ -90,4 +141,32 @@ export function useRealtime({
       }
     };
   }, [enabled]);
+
+  useEffect(() => {
+    const ws = wsRef.current;
+    if (ws?.readyState === WebSocket.OPEN) sendSubscriptions(ws, subsRef.current);
+  }, [subsKey]);
+
+  const send = useCallback((message) => {
+    const ws = wsRef.current;
+    if (ws?.readyState !== WebSocket.OPEN) return false;
+    try {
+      ws.send(JSON.stringify(message));
+      return true;
+    } catch {
+      return false;
+    }
+  }, []);
+
+  return { send };
+}
+
+function sendSubscriptions(ws, subscriptions) {
+  for (const sub of subscriptions || []) {
+    try {
+      ws.send(JSON.stringify({ action: "subscribe", ...sub }));
+    } catch {
+      /* */
+    }
+  }
 }
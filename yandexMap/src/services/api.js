@@ -579,7 +579,8 @@ export const patchMarkerStatus = async (
   markerId,
   status,
   moderatorNote,
-  imageAfterUrl
+  imageAfterUrl,
+  { force = false } = {}
 ) => {
   const token = getToken();
   if (!token) {
@@ -592,6 +593,7 @@ export const patchMarkerStatus = async (
   if (imageAfterUrl != null && String(imageAfterUrl).trim() !== "") {
     body.image_after_url = String(imageAfterUrl).trim();
   }
+  if (force) body.force = true;
   const response = await fetch(`${API_URL}/markers/${markerId}/status`, {
     method: "PATCH",
     headers: {
@@ -606,7 +608,16 @@ export const patchMarkerStatus = async (
   }
   if (!response.ok) {
     const errorText = await response.text();
-    throw new Error(errorText || "Не удалось обновить статус");
+    let data = null;
+    try {
+      data = JSON.parse(errorText);
+    } catch {
+      /* не JSON */
+    }
+    const err = new Error(data?.error || errorText || "Не удалось обновить статус");
+    // marker_locked — обращение проверяет другой модератор, можно повторить с force
+    if (data?.code) err.code = data.code;
+    throw err;
   }
   return response.json();
 };